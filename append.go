@@ -0,0 +1,245 @@
+package msgpack
+
+import (
+	"math"
+	"time"
+)
+
+// The Append* functions append the encoding of a value to dst and return
+// the extended buffer, in the manner of strconv.AppendInt. The Pack*
+// functions are built on them.
+
+func AppendUInt64(dst []byte, value uint64) []byte {
+	switch {
+	case value <= MAX_7BIT:
+		return append(dst, MP_FIXNUM|uint8(value))
+	case value <= MAX_8BIT:
+		return append(dst, MP_UINT8, uint8(value))
+	case value <= MAX_16BIT:
+		return append(dst, MP_UINT16, uint8(value>>8), uint8(value))
+	case value <= MAX_32BIT:
+		return append(dst, MP_UINT32,
+			uint8(value>>24), uint8(value>>16), uint8(value>>8), uint8(value))
+	default:
+		return append(dst, MP_UINT64,
+			uint8(value>>56), uint8(value>>48), uint8(value>>40), uint8(value>>32),
+			uint8(value>>24), uint8(value>>16), uint8(value>>8), uint8(value))
+	}
+}
+
+func AppendUInt32(dst []byte, value uint32) []byte {
+	return AppendUInt64(dst, uint64(value))
+}
+
+func AppendUInt16(dst []byte, value uint16) []byte {
+	return AppendUInt64(dst, uint64(value))
+}
+
+func AppendUInt8(dst []byte, value uint8) []byte {
+	return AppendUInt64(dst, uint64(value))
+}
+
+func AppendUInt(dst []byte, value uint) []byte {
+	return AppendUInt64(dst, uint64(value))
+}
+
+func AppendInt64(dst []byte, value int64) []byte {
+	n := uint64(value)
+	if value >= 0 {
+		switch {
+		case value <= MAX_7BIT:
+			return append(dst, MP_FIXNUM|uint8(n))
+		case value <= MAX_15BIT:
+			return append(dst, MP_INT16, uint8(n>>8), uint8(n))
+		case value <= MAX_31BIT:
+			return append(dst, MP_INT32,
+				uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+		default:
+			return append(dst, MP_INT64,
+				uint8(n>>56), uint8(n>>48), uint8(n>>40), uint8(n>>32),
+				uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+		}
+	}
+
+	switch {
+	case value >= -(MAX_5BIT + 1):
+		return append(dst, MP_NEGATIVE_FIXNUM|uint8(n))
+	case value >= -(int64(MAX_7BIT) + 1):
+		return append(dst, MP_INT8, uint8(n))
+	case value >= -(int64(MAX_15BIT) + 1):
+		return append(dst, MP_INT16, uint8(n>>8), uint8(n))
+	case value >= -(int64(MAX_31BIT) + 1):
+		return append(dst, MP_INT32,
+			uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+	default:
+		return append(dst, MP_INT64,
+			uint8(n>>56), uint8(n>>48), uint8(n>>40), uint8(n>>32),
+			uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+	}
+}
+
+func AppendInt32(dst []byte, value int32) []byte {
+	return AppendInt64(dst, int64(value))
+}
+
+func AppendInt16(dst []byte, value int16) []byte {
+	return AppendInt64(dst, int64(value))
+}
+
+func AppendInt8(dst []byte, value int8) []byte {
+	return AppendInt64(dst, int64(value))
+}
+
+func AppendInt(dst []byte, value int) []byte {
+	return AppendInt64(dst, int64(value))
+}
+
+func AppendNil(dst []byte) []byte {
+	return append(dst, MP_NULL)
+}
+
+func AppendBool(dst []byte, value bool) []byte {
+	if value {
+		return append(dst, MP_TRUE)
+	}
+	return append(dst, MP_FALSE)
+}
+
+func AppendFloat(dst []byte, value float32) []byte {
+	n := math.Float32bits(value)
+	return append(dst, MP_FLOAT,
+		uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+}
+
+func AppendDouble(dst []byte, value float64) []byte {
+	n := math.Float64bits(value)
+	return append(dst, MP_DOUBLE,
+		uint8(n>>56), uint8(n>>48), uint8(n>>40), uint8(n>>32),
+		uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+}
+
+func appendRawHeader(dst []byte, length uint64) []byte {
+	switch {
+	case length <= MAX_5BIT:
+		return append(dst, MP_FIXRAW|uint8(length))
+	case length <= MAX_16BIT:
+		return append(dst, MP_RAW16, uint8(length>>8), uint8(length))
+	default:
+		return append(dst, MP_RAW32,
+			uint8(length>>24), uint8(length>>16), uint8(length>>8), uint8(length))
+	}
+}
+
+func AppendRawBuffer(dst []byte, value []uint8) []byte {
+	return append(appendRawHeader(dst, uint64(len(value))), value...)
+}
+
+func appendStringHeader(dst []byte, length uint64) []byte {
+	switch {
+	case length <= MAX_5BIT:
+		return append(dst, MP_FIXSTR|uint8(length))
+	case length <= MAX_8BIT:
+		return append(dst, MP_STR8, uint8(length))
+	case length <= MAX_16BIT:
+		return append(dst, MP_STR16, uint8(length>>8), uint8(length))
+	default:
+		return append(dst, MP_STR32,
+			uint8(length>>24), uint8(length>>16), uint8(length>>8), uint8(length))
+	}
+}
+
+func AppendString(dst []byte, value string) []byte {
+	return append(appendStringHeader(dst, uint64(len(value))), value...)
+}
+
+func appendBinaryHeader(dst []byte, length uint64) []byte {
+	switch {
+	case length <= MAX_8BIT:
+		return append(dst, MP_BIN8, uint8(length))
+	case length <= MAX_16BIT:
+		return append(dst, MP_BIN16, uint8(length>>8), uint8(length))
+	default:
+		return append(dst, MP_BIN32,
+			uint8(length>>24), uint8(length>>16), uint8(length>>8), uint8(length))
+	}
+}
+
+func AppendBinary(dst []byte, value []uint8) []byte {
+	return append(appendBinaryHeader(dst, uint64(len(value))), value...)
+}
+
+func AppendArrayHeader(dst []byte, length uint32) []byte {
+	switch {
+	case length <= MAX_4BIT:
+		return append(dst, MP_FIXARRAY|uint8(length))
+	case length <= MAX_16BIT:
+		return append(dst, MP_ARRAY16, uint8(length>>8), uint8(length))
+	default:
+		return append(dst, MP_ARRAY32,
+			uint8(length>>24), uint8(length>>16), uint8(length>>8), uint8(length))
+	}
+}
+
+func AppendMapHeader(dst []byte, length uint32) []byte {
+	switch {
+	case length <= MAX_4BIT:
+		return append(dst, MP_FIXMAP|uint8(length))
+	case length <= MAX_16BIT:
+		return append(dst, MP_MAP16, uint8(length>>8), uint8(length))
+	default:
+		return append(dst, MP_MAP32,
+			uint8(length>>24), uint8(length>>16), uint8(length>>8), uint8(length))
+	}
+}
+
+func appendExtHeader(dst []byte, typeCode int8, length uint64) []byte {
+	switch {
+	case length == 1:
+		return append(dst, MP_FIXEXT1, uint8(typeCode))
+	case length == 2:
+		return append(dst, MP_FIXEXT2, uint8(typeCode))
+	case length == 4:
+		return append(dst, MP_FIXEXT4, uint8(typeCode))
+	case length == 8:
+		return append(dst, MP_FIXEXT8, uint8(typeCode))
+	case length == 16:
+		return append(dst, MP_FIXEXT16, uint8(typeCode))
+	case length <= MAX_8BIT:
+		return append(dst, MP_EXT8, uint8(length), uint8(typeCode))
+	case length <= MAX_16BIT:
+		return append(dst, MP_EXT16, uint8(length>>8), uint8(length), uint8(typeCode))
+	default:
+		return append(dst, MP_EXT32,
+			uint8(length>>24), uint8(length>>16), uint8(length>>8), uint8(length),
+			uint8(typeCode))
+	}
+}
+
+func AppendExt(dst []byte, typeCode int8, payload []byte) []byte {
+	return append(appendExtHeader(dst, typeCode, uint64(len(payload))), payload...)
+}
+
+// AppendTime writes t as timestamp32 when it has no fraction and fits in 32
+// bits of seconds, as timestamp64 when the seconds fit in 34 bits, and as
+// timestamp96 otherwise.
+func AppendTime(dst []byte, t time.Time) []byte {
+	sec := t.Unix()
+	nsec := uint64(t.Nanosecond())
+
+	if uint64(sec)>>34 == 0 {
+		n := nsec<<34 | uint64(sec)
+		if n&0xffffffff00000000 == 0 {
+			return append(dst, MP_FIXEXT4, uint8(MP_EXT_TIMESTAMP&0xff),
+				uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+		}
+		return append(dst, MP_FIXEXT8, uint8(MP_EXT_TIMESTAMP&0xff),
+			uint8(n>>56), uint8(n>>48), uint8(n>>40), uint8(n>>32),
+			uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+	}
+
+	s := uint64(sec)
+	return append(dst, MP_EXT8, 12, uint8(MP_EXT_TIMESTAMP&0xff),
+		uint8(nsec>>24), uint8(nsec>>16), uint8(nsec>>8), uint8(nsec),
+		uint8(s>>56), uint8(s>>48), uint8(s>>40), uint8(s>>32),
+		uint8(s>>24), uint8(s>>16), uint8(s>>8), uint8(s))
+}
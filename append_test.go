@@ -0,0 +1,92 @@
+package msgpack
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestAppendMatchesPack(t *testing.T) {
+	b := &bytes.Buffer{}
+	var dst []byte
+
+	for _, i := range []uint64{0, 127, 128, 255, 256, 65535, 65536, 4294967295, 4294967296} {
+		PackUInt64(b, i)
+		dst = AppendUInt64(dst, i)
+		PackUInt32(b, uint32(i))
+		dst = AppendUInt32(dst, uint32(i))
+	}
+
+	for _, i := range []int64{0, 127, 128, 32767, 32768, 2147483647, 2147483648,
+		-1, -32, -33, -128, -129, -32768, -32769, -2147483648, -2147483649} {
+		PackInt64(b, i)
+		dst = AppendInt64(dst, i)
+		PackInt32(b, int32(i))
+		dst = AppendInt32(dst, int32(i))
+	}
+
+	PackNil(b)
+	dst = AppendNil(dst)
+	PackBool(b, true)
+	dst = AppendBool(dst, true)
+	PackBool(b, false)
+	dst = AppendBool(dst, false)
+	PackFloat(b, 3.1415926)
+	dst = AppendFloat(dst, 3.1415926)
+	PackDouble(b, 3.1416786785926)
+	dst = AppendDouble(dst, 3.1416786785926)
+
+	for _, n := range []int{0, 31, 32, 255, 256, 65535, 65536} {
+		v := bytes.Repeat([]byte{'x'}, n)
+		PackRawBuffer(b, v)
+		dst = AppendRawBuffer(dst, v)
+		PackString(b, string(v))
+		dst = AppendString(dst, string(v))
+		PackBinary(b, v)
+		dst = AppendBinary(dst, v)
+		PackExt(b, 3, v)
+		dst = AppendExt(dst, 3, v)
+	}
+
+	for _, n := range []uint32{0, 15, 16, 65535, 65536} {
+		PackArrayHeader(b, n)
+		dst = AppendArrayHeader(dst, n)
+		PackMapHeader(b, n)
+		dst = AppendMapHeader(dst, n)
+	}
+
+	for _, v := range []time.Time{time.Unix(1, 0), time.Unix(1, 1), time.Unix(-1, 0)} {
+		PackTime(b, v)
+		dst = AppendTime(dst, v)
+	}
+
+	if bytes.Compare(b.Bytes(), dst) != 0 {
+		t.Error("Append output differs from Pack output")
+	}
+}
+
+func TestAppendInt64(t *testing.T) {
+	dst := []byte{0xaa}
+	dst = AppendInt64(dst, -129)
+	dst = AppendInt64(dst, 1)
+
+	if bytes.Compare(dst, []byte{0xaa, 0xd1, 0xff, 0x7f, 0x1}) != 0 {
+		t.Error("wrong output", dst)
+	}
+}
+
+func TestAppendAllocs(t *testing.T) {
+	dst := make([]byte, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = dst[:0]
+		dst = AppendUInt64(dst, 4294967296)
+		dst = AppendInt64(dst, -129)
+		dst = AppendDouble(dst, 1.5)
+		dst = AppendString(dst, "hello")
+		dst = AppendMapHeader(dst, 3)
+	})
+
+	if allocs != 0 {
+		t.Error("Append allocates", allocs)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+const maxDumpDepth = 10000
+
+// dumpAll prints every value of input as an indented tree, one element per
+// line, each with its type:
+//
+//	map[2]
+//	  raw "id" => uint 1
+//	  raw "tags" => array[1]
+//	    raw "a"
+func dumpAll(input []byte, stdout io.Writer) error {
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+
+	c := msgpack.NewCursor(input)
+	for n := 0; c.Len() > 0; n++ {
+		start := c.Offset()
+		if err := dumpValue(w, c, 0, ""); err != nil {
+			return valueError(n, start, err)
+		}
+	}
+	return nil
+}
+
+func dumpValue(w *bufio.Writer, c *msgpack.Cursor, depth int, prefix string) error {
+	if depth > maxDumpDepth {
+		return errors.New("values nested more than " + strconv.Itoa(maxDumpDepth) + " deep")
+	}
+
+	info, err := c.NextType()
+	if err != nil {
+		return err
+	}
+
+	w.WriteString(strings.Repeat("  ", depth))
+	w.WriteString(prefix)
+
+	switch info.Type {
+	case msgpack.ArrayType:
+		n, err := c.UnpackArrayHeader()
+		if err != nil {
+			return err
+		}
+		w.WriteString("array[" + strconv.FormatUint(uint64(n), 10) + "]\n")
+
+		for i := uint32(0); i < n; i++ {
+			if err = dumpValue(w, c, depth+1, ""); err != nil {
+				return err
+			}
+		}
+	case msgpack.MapType:
+		n, err := c.UnpackMapHeader()
+		if err != nil {
+			return err
+		}
+		w.WriteString("map[" + strconv.FormatUint(uint64(n), 10) + "]\n")
+
+		for i := uint32(0); i < n; i++ {
+			key, err := c.NextType()
+			if err != nil {
+				return err
+			}
+
+			// Keys that fit on a line go in front of their value.
+			if key.Type != msgpack.ArrayType && key.Type != msgpack.MapType {
+				s, err := scalarText(c)
+				if err != nil {
+					return err
+				}
+				err = dumpValue(w, c, depth+1, s+" => ")
+			} else if err = dumpValue(w, c, depth+1, "key: "); err == nil {
+				err = dumpValue(w, c, depth+1, "value: ")
+			}
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		s, err := scalarText(c)
+		if err != nil {
+			return err
+		}
+		w.WriteString(s + "\n")
+	}
+	return nil
+}
+
+// scalarText reads a value that is not an array or a map and returns its
+// type and value as text.
+func scalarText(c *msgpack.Cursor) (string, error) {
+	info, err := c.NextType()
+	if err != nil {
+		return "", err
+	}
+
+	switch info.Type {
+	case msgpack.NilType:
+		return "nil", c.UnpackNil()
+	case msgpack.BoolType:
+		v, err := c.UnpackBool()
+		return "bool " + strconv.FormatBool(v), err
+	case msgpack.IntType:
+		v, err := c.UnpackInt64()
+		return "int " + strconv.FormatInt(v, 10), err
+	case msgpack.UintType:
+		v, err := c.UnpackUInt64()
+		return "uint " + strconv.FormatUint(v, 10), err
+	case msgpack.Float32Type:
+		v, err := c.UnpackFloat()
+		return "float32 " + strconv.FormatFloat(float64(v), 'g', -1, 32), err
+	case msgpack.Float64Type:
+		v, err := c.UnpackDouble()
+		return "float64 " + strconv.FormatFloat(v, 'g', -1, 64), err
+	case msgpack.RawType:
+		v, err := c.UnpackRawBuffer()
+		return "raw " + strconv.Quote(string(v)), err
+	case msgpack.BinType:
+		v, err := c.UnpackRawBuffer()
+		return "bin[" + strconv.Itoa(len(v)) + "] " + hex.EncodeToString(v), err
+	case msgpack.ExtType:
+		if info.ExtType == msgpack.MP_EXT_TIMESTAMP {
+			if t, err := c.UnpackTime(); err == nil {
+				return "timestamp " + t.UTC().Format(time.RFC3339Nano), nil
+			}
+		}
+		code, payload, err := c.UnpackExt()
+		return "ext[" + strconv.Itoa(len(payload)) + "] type " + strconv.Itoa(int(code)) + ": " +
+			hex.EncodeToString(payload), err
+	}
+	return "", c.Skip()
+}
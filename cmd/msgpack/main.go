@@ -0,0 +1,307 @@
+// Command msgpack prints, converts and checks MessagePack data.
+//
+// Usage:
+//
+//	msgpack <command> [flags] [file]
+//
+// The commands are:
+//
+//	dump      print each value as a tree with the type of every element
+//	explain   print the input header by header, marking bytes that do not decode
+//	tojson    write each value as a line of JSON
+//	fromjson  write each JSON value read as MessagePack
+//	validate  check that the input is a sequence of well formed values
+//
+// The input is read from file, or from stdin when file is missing or "-".
+// It may hold a single value or several values one after the other. With
+// -hex or -base64 the input is text in that encoding, and -e gives the
+// input on the command line instead:
+//
+//	msgpack dump -hex -e "82 a2 69 64 01 a4 74 61 67 73 91 a1 61"
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+const usage = `usage: msgpack <command> [flags] [file]
+
+Commands:
+  dump      print each value as a tree with the type of every element
+  explain   print the input header by header, marking bytes that do not decode
+  tojson    write each value as a line of JSON
+  fromjson  write each JSON value read as MessagePack
+  validate  check that the input is a sequence of well formed values
+
+Run msgpack <command> -h for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type command struct {
+	name  string
+	flags *flag.FlagSet
+	run   func(input []byte, stdout io.Writer) error
+
+	hex, base64 bool
+	data        string
+	textInput   bool // the input is JSON, not MessagePack
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	cmd := &command{name: args[0], flags: flag.NewFlagSet(args[0], flag.ContinueOnError)}
+	cmd.flags.SetOutput(stderr)
+	cmd.flags.BoolVar(&cmd.hex, "hex", false, "the input is hex text; spaces, commas and 0x prefixes are ignored")
+	cmd.flags.BoolVar(&cmd.base64, "base64", false, "the input is base64 text, in the standard or URL alphabet")
+	cmd.flags.StringVar(&cmd.data, "e", "", "take the input from this argument instead of a file")
+
+	switch cmd.name {
+	case "dump":
+		cmd.run = dumpAll
+	case "explain":
+		cmd.run = msgpack.Explain
+	case "tojson":
+		cmd.run = toJSONCommand(cmd.flags)
+	case "fromjson":
+		cmd.run = fromJSONCommand(cmd.flags)
+		cmd.textInput = true
+	case "validate":
+		cmd.run = validate
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "msgpack: unknown command %q\n\n%s", cmd.name, usage)
+		return 2
+	}
+
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	input, err := cmd.readInput(stdin)
+	if err == nil {
+		err = cmd.run(input, stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "msgpack %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func (cmd *command) readInput(stdin io.Reader) ([]byte, error) {
+	if cmd.hex && cmd.base64 {
+		return nil, errors.New("-hex and -base64 cannot be used together")
+	}
+	if cmd.textInput && (cmd.hex || cmd.base64) {
+		return nil, errors.New("the input is JSON; -hex and -base64 do not apply")
+	}
+
+	var input []byte
+	var err error
+	switch name := cmd.flags.Arg(0); {
+	case cmd.flags.NArg() > 1:
+		return nil, errors.New("too many arguments")
+	case cmd.data != "":
+		if name != "" {
+			return nil, errors.New("cannot read both -e and " + name)
+		}
+		input = []byte(cmd.data)
+	case name == "" || name == "-":
+		input, err = io.ReadAll(stdin)
+	default:
+		input, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cmd.hex:
+		return decodeHex(input)
+	case cmd.base64:
+		return decodeBase64(input)
+	}
+	return input, nil
+}
+
+func decodeHex(text []byte) ([]byte, error) {
+	fields := strings.FieldsFunc(string(text), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ','
+	})
+
+	var digits strings.Builder
+	for _, f := range fields {
+		if strings.HasPrefix(f, "0x") || strings.HasPrefix(f, "0X") {
+			f = f[2:]
+		}
+		digits.WriteString(f)
+	}
+
+	b, err := hex.DecodeString(digits.String())
+	if err != nil {
+		return nil, fmt.Errorf("bad hex input: %v", err)
+	}
+	return b, nil
+}
+
+func decodeBase64(text []byte) ([]byte, error) {
+	s := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, string(text))
+
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if !strings.HasSuffix(s, "=") {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+
+	b, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("bad base64 input: %v", err)
+	}
+	return b, nil
+}
+
+// valueError reports err for value n of the input, which starts at offset
+// start.
+func valueError(n, start int, err error) error {
+	return fmt.Errorf("value %d at offset %d: %v", n, start, err)
+}
+
+func validate(input []byte, stdout io.Writer) error {
+	c := msgpack.NewCursor(input)
+	n := 0
+	for c.Len() > 0 {
+		start := c.Offset()
+		if err := c.Skip(); err != nil {
+			return valueError(n, start, err)
+		}
+		n++
+	}
+
+	if n == 0 {
+		return errors.New("no values in input")
+	}
+	fmt.Fprintf(stdout, "ok: %d values in %d bytes\n", n, len(input))
+	return nil
+}
+
+var (
+	binaryModes = map[string]msgpack.JSONBinaryMode{
+		"base64": msgpack.JSONBinaryBase64,
+		"array":  msgpack.JSONBinaryArray,
+		"error":  msgpack.JSONBinaryError,
+	}
+	keyModes = map[string]msgpack.JSONKeyMode{
+		"string": msgpack.JSONKeysString,
+		"error":  msgpack.JSONKeysError,
+	}
+	floatModes = map[string]msgpack.JSONFloatMode{
+		"error":  msgpack.JSONFloatsError,
+		"null":   msgpack.JSONFloatsNull,
+		"string": msgpack.JSONFloatsString,
+	}
+	extModes = map[string]msgpack.JSONExtMode{
+		"object": msgpack.JSONExtObject,
+		"error":  msgpack.JSONExtError,
+	}
+)
+
+func toJSONCommand(flags *flag.FlagSet) func([]byte, io.Writer) error {
+	indent := flags.Bool("indent", false, "indent the JSON")
+	binary := flags.String("binary", "base64", "write bin data as base64, array or error")
+	keys := flags.String("keys", "string", "write map keys that are not strings as string or error")
+	floats := flags.String("floats", "error", "write NaN and infinite floats as error, null or string")
+	ext := flags.String("ext", "object", "write ext values as object or error")
+
+	return func(input []byte, stdout io.Writer) error {
+		var opts msgpack.JSONOptions
+		var ok [4]bool
+		opts.Binary, ok[0] = binaryModes[*binary]
+		opts.Keys, ok[1] = keyModes[*keys]
+		opts.Floats, ok[2] = floatModes[*floats]
+		opts.Ext, ok[3] = extModes[*ext]
+		for i, name := range []string{"binary", "keys", "floats", "ext"} {
+			if !ok[i] {
+				return fmt.Errorf("bad value for -%s", name)
+			}
+		}
+
+		c := msgpack.NewCursor(input)
+		var out []byte
+		var pretty bytes.Buffer
+		for n := 0; c.Len() > 0; n++ {
+			start := c.Offset()
+			var err error
+			if out, err = c.AppendJSON(out[:0], opts); err != nil {
+				return valueError(n, start, err)
+			}
+
+			if *indent {
+				pretty.Reset()
+				if err = json.Indent(&pretty, out, "", "  "); err != nil {
+					return err
+				}
+				out = append(out[:0], pretty.Bytes()...)
+			}
+			if _, err = stdout.Write(append(out, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func fromJSONCommand(flags *flag.FlagSet) func([]byte, io.Writer) error {
+	output := flags.String("out", "raw", "write the MessagePack data as raw, hex or base64")
+
+	return func(input []byte, stdout io.Writer) error {
+		if *output != "raw" && *output != "hex" && *output != "base64" {
+			return errors.New("-out must be one of raw, hex, base64")
+		}
+
+		var buf bytes.Buffer
+		if err := msgpack.JSONToMsgpack(&buf, bytes.NewReader(input)); err != nil {
+			return err
+		}
+
+		var err error
+		switch *output {
+		case "hex":
+			_, err = fmt.Fprintf(stdout, "%x\n", buf.Bytes())
+		case "base64":
+			_, err = fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		default:
+			_, err = stdout.Write(buf.Bytes())
+		}
+		return err
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestDump(t *testing.T) {
+	b := msgpack.AppendMapHeader(nil, 3)
+	b = msgpack.AppendString(b, "id")
+	b = msgpack.AppendUInt64(b, 300)
+	b = msgpack.AppendString(b, "tags")
+	b = msgpack.AppendArrayHeader(b, 2)
+	b = msgpack.AppendBinary(b, []byte{1, 2})
+	b = msgpack.AppendExt(b, 5, []byte{0xff})
+	b = msgpack.AppendArrayHeader(b, 1)
+	b = msgpack.AppendNil(b)
+	b = msgpack.AppendDouble(b, -1.5)
+	b = msgpack.AppendInt64(b, -3)
+
+	code, out, errOut := runCommand(t, string(b), "dump")
+	want := `map[3]
+  raw "id" => uint 300
+  raw "tags" => array[2]
+    bin[2] 0102
+    ext[1] type 5: ff
+  key: array[1]
+    nil
+  value: float64 -1.5
+int -3
+`
+	if code != 0 || out != want || errOut != "" {
+		t.Errorf("wrong output %d\n%s%s", code, out, errOut)
+	}
+}
+
+func TestDumpError(t *testing.T) {
+	code, out, errOut := runCommand(t, "", "dump", "-hex", "-e", "01 92 c3")
+	if code != 1 || out != "int 1\narray[2]\n  bool true\n" ||
+		!strings.Contains(errOut, "value 1 at offset 1: unpack overflow") {
+		t.Errorf("wrong output %d\n%s%s", code, out, errOut)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	code, out, errOut := runCommand(t, "", "explain", "-hex", "-e", "92 c1 01")
+	want := ` offset depth  bytes                       format          value
+ 0      0      92                          fixarray        len 2
+!1      1      c1                            invalid         error: invalid type header 0xc1 at offset 1, expected any type
+ 2      1      01                            fixnum          1
+`
+	if code != 1 || out != want || !strings.Contains(errOut, "invalid type header 0xc1") {
+		t.Errorf("wrong output %d\n%s%s", code, out, errOut)
+	}
+}
+
+func TestInputFormats(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "in.msgpack")
+	if err := os.WriteFile(file, []byte{0x93, 0x1, 0x2, 0x3}, 0644); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	for _, args := range [][]string{
+		{file},
+		{"-hex", "-e", "0x93, 0x01, 0x02, 0x03"},
+		{"-hex", "-e", "93010203\n"},
+		{"-base64", "-e", "kwECAw=="},
+		{"-base64", "-e", "kwECAw"},
+	} {
+		code, out, errOut := runCommand(t, "", append([]string{"tojson"}, args...)...)
+		if code != 0 || out != "[1,2,3]\n" {
+			t.Errorf("wrong output for %v: %d %s%s", args, code, out, errOut)
+		}
+	}
+
+	code, out, _ := runCommand(t, "\x93\x01\x02\x03", "tojson", "-")
+	if code != 0 || out != "[1,2,3]\n" {
+		t.Error("wrong output for stdin", code, out)
+	}
+
+	for _, args := range [][]string{
+		{"-hex", "-e", "9"},
+		{"-base64", "-e", "!!"},
+		{"-hex", "-base64", "-e", "00"},
+		{"-e", "00", file},
+		{file, file},
+		{filepath.Join(dir, "missing")},
+	} {
+		if code, _, _ := runCommand(t, "", append([]string{"validate"}, args...)...); code != 1 {
+			t.Errorf("expected error for %v: %d", args, code)
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	code, out, errOut := runCommand(t, "", "tojson", "-hex", "-e", "82 a1 61 c4 01 ff a1 62 cb 7f f8 00 00 00 00 00 00 c0")
+	if code != 1 || !strings.Contains(errOut, "value 0 at offset 0: cannot write float NaN at offset 8 as JSON") {
+		t.Errorf("wrong output %d %s%s", code, out, errOut)
+	}
+
+	code, out, errOut = runCommand(t, "", "tojson", "-floats", "null", "-binary", "array", "-indent",
+		"-hex", "-e", "82 a1 61 c4 01 ff a1 62 cb 7f f8 00 00 00 00 00 00 c0")
+	if code != 0 || out != "{\n  \"a\": [\n    255\n  ],\n  \"b\": null\n}\nnull\n" {
+		t.Errorf("wrong output %d\n%s%s", code, out, errOut)
+	}
+
+	if code, _, _ = runCommand(t, "", "tojson", "-floats", "zero", "-e", "0"); code != 1 {
+		t.Error("expected error for bad -floats", code)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	in := `{"a": [1, -1, 18446744073709551615]} "x"`
+	code, out, errOut := runCommand(t, in, "fromjson", "-out", "hex")
+	if code != 0 || out != "81a1619301ffcfffffffffffffffffa178\n" {
+		t.Errorf("wrong output %d %s%s", code, out, errOut)
+	}
+
+	code, out, _ = runCommand(t, "[true]", "fromjson")
+	if code != 0 || out != "\x91\xc3" {
+		t.Errorf("wrong output %d %q", code, out)
+	}
+
+	code, out, _ = runCommand(t, "[true]", "fromjson", "-out", "base64")
+	if code != 0 || out != "kcM=\n" {
+		t.Errorf("wrong output %d %q", code, out)
+	}
+
+	for _, args := range [][]string{{"-hex"}, {"-out", "xml"}} {
+		if code, _, _ := runCommand(t, "1", append([]string{"fromjson"}, args...)...); code != 1 {
+			t.Errorf("expected error for %v: %d", args, code)
+		}
+	}
+	if code, _, _ := runCommand(t, "[1", "fromjson"); code != 1 {
+		t.Error("expected error for bad JSON", code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	code, out, _ := runCommand(t, "", "validate", "-hex", "-e", "c0 92 01 02 a1 61")
+	if code != 0 || out != "ok: 3 values in 6 bytes\n" {
+		t.Errorf("wrong output %d %s", code, out)
+	}
+
+	for _, in := range []string{"", "c1", "92 01", "c0 d9"} {
+		if code, _, _ := runCommand(t, "", "validate", "-hex", "-e", in+" "); code != 1 {
+			t.Errorf("expected error for %q: %d", in, code)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, errOut := runCommand(t, ""); code != 2 || !strings.HasPrefix(errOut, "usage:") {
+		t.Error("wrong output", code, errOut)
+	}
+	if code, _, errOut := runCommand(t, "", "unpack"); code != 2 || !strings.Contains(errOut, "unknown command") {
+		t.Error("wrong output", code, errOut)
+	}
+	if code, _, _ := runCommand(t, "", "dump", "-x"); code != 2 {
+		t.Error("wrong exit code for bad flag", code)
+	}
+}
@@ -0,0 +1,52 @@
+// Package example holds types with methods written by msgpackgen. The
+// generated files are checked in and kept up to date by the tests of the
+// generator.
+package example
+
+import (
+	"time"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+//go:generate go run .. -type Order
+
+type Status int8
+
+const (
+	Pending Status = iota
+	Shipped
+)
+
+type Tags []string
+
+type Item struct {
+	SKU      string  `msgpack:"sku"`
+	Quantity uint16  `msgpack:"qty"`
+	Price    float64 `msgpack:"price,omitempty"`
+	Data     []byte  `msgpack:"data,omitempty"`
+}
+
+type Base struct {
+	ID      uint64    `msgpack:"id"`
+	Created time.Time `msgpack:"created"`
+}
+
+type Order struct {
+	Base
+	Customer   string             `json:"customer"`
+	Status     Status             `msgpack:"status"`
+	Items      []Item             `msgpack:"items"`
+	Gift       *Item              `msgpack:"gift,omitempty"`
+	Tags       Tags               `msgpack:"tags,omitempty"`
+	Totals     map[string]float32 `msgpack:"totals"`
+	Counts     map[int]*uint      `msgpack:"counts,omitempty"`
+	Hash       [4]byte            `msgpack:"hash"`
+	Matrix     [2][2]int16        `msgpack:"matrix"`
+	Paid       bool               `msgpack:"paid,omitempty"`
+	Extra      interface{}        `msgpack:"extra,omitempty"`
+	Meta       msgpack.RawMessage `msgpack:"meta,omitempty"`
+	Attachment msgpack.Ext        `msgpack:"attachment"`
+	Note       string             `msgpack:"-"`
+	internal   int
+}
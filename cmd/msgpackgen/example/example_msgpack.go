@@ -0,0 +1,701 @@
+// Code generated by msgpackgen. DO NOT EDIT.
+
+package example
+
+import (
+	"sort"
+	"strings"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+var msgpackFieldsOrder = [...]string{
+	"id",
+	"created",
+	"customer",
+	"status",
+	"items",
+	"gift",
+	"tags",
+	"totals",
+	"counts",
+	"hash",
+	"matrix",
+	"paid",
+	"extra",
+	"meta",
+	"attachment",
+}
+
+// MarshalMsg appends the MessagePack encoding of z to b, as msgpack.Marshal
+// would write it.
+func (z *Order) MarshalMsg(b []byte) ([]byte, error) {
+	var err error
+	n := uint32(15)
+	if z.Gift == nil {
+		n--
+	}
+	if len(z.Tags) == 0 {
+		n--
+	}
+	if len(z.Counts) == 0 {
+		n--
+	}
+	if !z.Paid {
+		n--
+	}
+	if z.Extra == nil {
+		n--
+	}
+	if len(z.Meta) == 0 {
+		n--
+	}
+	b = msgpack.AppendMapHeader(b, n)
+	b = msgpack.AppendString(b, "id")
+	b = msgpack.AppendUInt64(b, z.Base.ID)
+	b = msgpack.AppendString(b, "created")
+	b = msgpack.AppendTime(b, z.Base.Created)
+	b = msgpack.AppendString(b, "customer")
+	b = msgpack.AppendString(b, z.Customer)
+	b = msgpack.AppendString(b, "status")
+	b = msgpack.AppendInt64(b, int64(z.Status))
+	b = msgpack.AppendString(b, "items")
+	if z.Items == nil {
+		b = msgpack.AppendNil(b)
+	} else {
+		b = msgpack.AppendArrayHeader(b, uint32(len(z.Items)))
+		for i1 := range z.Items {
+			if b, err = z.Items[i1].MarshalMsg(b); err != nil {
+				return b, err
+			}
+		}
+	}
+	if z.Gift != nil {
+		b = msgpack.AppendString(b, "gift")
+		if b, err = z.Gift.MarshalMsg(b); err != nil {
+			return b, err
+		}
+	}
+	if len(z.Tags) != 0 {
+		b = msgpack.AppendString(b, "tags")
+		b = msgpack.AppendArrayHeader(b, uint32(len(z.Tags)))
+		for i2 := range z.Tags {
+			b = msgpack.AppendString(b, z.Tags[i2])
+		}
+	}
+	b = msgpack.AppendString(b, "totals")
+	if z.Totals == nil {
+		b = msgpack.AppendNil(b)
+	} else {
+		b = msgpack.AppendMapHeader(b, uint32(len(z.Totals)))
+		keys5 := make([]string, 0, len(z.Totals))
+		for k3 := range z.Totals {
+			keys5 = append(keys5, k3)
+		}
+		sort.Slice(keys5, func(i, j int) bool { return keys5[i] < keys5[j] })
+		for _, k3 := range keys5 {
+			e4 := z.Totals[k3]
+			b = msgpack.AppendString(b, k3)
+			b = msgpack.AppendFloat(b, e4)
+		}
+	}
+	if len(z.Counts) != 0 {
+		b = msgpack.AppendString(b, "counts")
+		b = msgpack.AppendMapHeader(b, uint32(len(z.Counts)))
+		keys8 := make([]int, 0, len(z.Counts))
+		for k6 := range z.Counts {
+			keys8 = append(keys8, k6)
+		}
+		sort.Slice(keys8, func(i, j int) bool { return keys8[i] < keys8[j] })
+		for _, k6 := range keys8 {
+			e7 := z.Counts[k6]
+			b = msgpack.AppendInt64(b, int64(k6))
+			if e7 == nil {
+				b = msgpack.AppendNil(b)
+			} else {
+				b = msgpack.AppendUInt64(b, uint64(*e7))
+			}
+		}
+	}
+	b = msgpack.AppendString(b, "hash")
+	b = msgpack.AppendBinary(b, z.Hash[:])
+	b = msgpack.AppendString(b, "matrix")
+	b = msgpack.AppendArrayHeader(b, uint32(len(z.Matrix)))
+	for i9 := range z.Matrix {
+		b = msgpack.AppendArrayHeader(b, uint32(len(z.Matrix[i9])))
+		for i10 := range z.Matrix[i9] {
+			b = msgpack.AppendInt64(b, int64(z.Matrix[i9][i10]))
+		}
+	}
+	if z.Paid {
+		b = msgpack.AppendString(b, "paid")
+		b = msgpack.AppendBool(b, z.Paid)
+	}
+	if z.Extra != nil {
+		b = msgpack.AppendString(b, "extra")
+		x11, err := msgpack.Marshal(&z.Extra)
+		if err != nil {
+			return b, err
+		}
+		b = append(b, x11...)
+	}
+	if len(z.Meta) != 0 {
+		b = msgpack.AppendString(b, "meta")
+		b = append(b, z.Meta...)
+	}
+	b = msgpack.AppendString(b, "attachment")
+	b = msgpack.AppendExt(b, z.Attachment.Type, z.Attachment.Data)
+	return b, nil
+}
+
+// UnmarshalMsg decodes a value from the start of b into z, as
+// msgpack.Unmarshal would, and returns the bytes after it.
+func (z *Order) UnmarshalMsg(b []byte) ([]byte, error) {
+	c := msgpack.NewCursor(b)
+	if err := z.unmarshalMsg(b, c, 0); err != nil {
+		return b, err
+	}
+	return b[c.Offset():], nil
+}
+
+// unmarshalMsg decodes z inside depth arrays and maps.
+func (z *Order) unmarshalMsg(b []byte, c *msgpack.Cursor, depth int) error {
+	if c.IsNil() {
+		c.UnpackNil()
+		return nil
+	}
+	if depth == msgpack.MaxDepth {
+		return &msgpack.DepthError{Offset: c.Offset()}
+	}
+	n, err := c.UnpackMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		key, err := c.UnpackRawBuffer()
+		if err != nil {
+			return err
+		}
+		var f int
+		switch string(key) {
+		case "id":
+			f = 1
+		case "created":
+			f = 2
+		case "customer":
+			f = 3
+		case "status":
+			f = 4
+		case "items":
+			f = 5
+		case "gift":
+			f = 6
+		case "tags":
+			f = 7
+		case "totals":
+			f = 8
+		case "counts":
+			f = 9
+		case "hash":
+			f = 10
+		case "matrix":
+			f = 11
+		case "paid":
+			f = 12
+		case "extra":
+			f = 13
+		case "meta":
+			f = 14
+		case "attachment":
+			f = 15
+		default:
+			for j, name := range msgpackFieldsOrder {
+				if strings.EqualFold(name, string(key)) {
+					f = j + 1
+					break
+				}
+			}
+		}
+		switch f {
+		case 1:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x12, err := c.UnpackUInt64()
+				if err != nil {
+					return err
+				}
+				z.Base.ID = x12
+			}
+		case 2:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x13, err := c.UnpackTime()
+				if err != nil {
+					return err
+				}
+				z.Base.Created = x13
+			}
+		case 3:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x14, err := c.UnpackRawBuffer()
+				if err != nil {
+					return err
+				}
+				z.Customer = string(x14)
+			}
+		case 4:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x15, err := c.UnpackInt8()
+				if err != nil {
+					return err
+				}
+				z.Status = Status(x15)
+			}
+		case 5:
+			if c.IsNil() {
+				c.UnpackNil()
+				z.Items = nil
+			} else {
+				if depth+1 == msgpack.MaxDepth {
+					return &msgpack.DepthError{Offset: c.Offset()}
+				}
+				n16, err := c.UnpackArrayHeader()
+				if err != nil {
+					return err
+				}
+				if uint64(n16) > uint64(c.Len()) {
+					return msgpack.ErrUnpackOverflow
+				}
+				s17 := make([]Item, n16)
+				for i18 := range s17 {
+					if err = s17[i18].unmarshalMsg(b, c, depth+2); err != nil {
+						return err
+					}
+				}
+				z.Items = s17
+			}
+		case 6:
+			if c.IsNil() {
+				c.UnpackNil()
+				z.Gift = nil
+			} else {
+				if z.Gift == nil {
+					z.Gift = new(Item)
+				}
+				if err = z.Gift.unmarshalMsg(b, c, depth+1); err != nil {
+					return err
+				}
+			}
+		case 7:
+			if c.IsNil() {
+				c.UnpackNil()
+				z.Tags = nil
+			} else {
+				if depth+1 == msgpack.MaxDepth {
+					return &msgpack.DepthError{Offset: c.Offset()}
+				}
+				n19, err := c.UnpackArrayHeader()
+				if err != nil {
+					return err
+				}
+				if uint64(n19) > uint64(c.Len()) {
+					return msgpack.ErrUnpackOverflow
+				}
+				s20 := make(Tags, n19)
+				for i21 := range s20 {
+					if c.IsNil() {
+						c.UnpackNil()
+					} else {
+						x22, err := c.UnpackRawBuffer()
+						if err != nil {
+							return err
+						}
+						s20[i21] = string(x22)
+					}
+				}
+				z.Tags = s20
+			}
+		case 8:
+			if c.IsNil() {
+				c.UnpackNil()
+				z.Totals = nil
+			} else {
+				if depth+1 == msgpack.MaxDepth {
+					return &msgpack.DepthError{Offset: c.Offset()}
+				}
+				n23, err := c.UnpackMapHeader()
+				if err != nil {
+					return err
+				}
+				if uint64(n23) > uint64(c.Len()) {
+					return msgpack.ErrUnpackOverflow
+				}
+				if z.Totals == nil {
+					z.Totals = make(map[string]float32, n23)
+				}
+				for i24 := uint32(0); i24 < n23; i24++ {
+					var k25 string
+					if c.IsNil() {
+						c.UnpackNil()
+					} else {
+						x27, err := c.UnpackRawBuffer()
+						if err != nil {
+							return err
+						}
+						k25 = string(x27)
+					}
+					var e26 float32
+					if c.IsNil() {
+						c.UnpackNil()
+					} else {
+						x28, err := c.UnpackFloat()
+						if err != nil {
+							return err
+						}
+						e26 = x28
+					}
+					z.Totals[k25] = e26
+				}
+			}
+		case 9:
+			if c.IsNil() {
+				c.UnpackNil()
+				z.Counts = nil
+			} else {
+				if depth+1 == msgpack.MaxDepth {
+					return &msgpack.DepthError{Offset: c.Offset()}
+				}
+				n29, err := c.UnpackMapHeader()
+				if err != nil {
+					return err
+				}
+				if uint64(n29) > uint64(c.Len()) {
+					return msgpack.ErrUnpackOverflow
+				}
+				if z.Counts == nil {
+					z.Counts = make(map[int]*uint, n29)
+				}
+				for i30 := uint32(0); i30 < n29; i30++ {
+					var k31 int
+					if c.IsNil() {
+						c.UnpackNil()
+					} else {
+						x33, err := c.UnpackInt()
+						if err != nil {
+							return err
+						}
+						k31 = x33
+					}
+					var e32 *uint
+					if c.IsNil() {
+						c.UnpackNil()
+						e32 = nil
+					} else {
+						if e32 == nil {
+							e32 = new(uint)
+						}
+						x34, err := c.UnpackUInt()
+						if err != nil {
+							return err
+						}
+						*e32 = x34
+					}
+					z.Counts[k31] = e32
+				}
+			}
+		case 10:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x35, err := c.UnpackRawBuffer()
+				if err != nil {
+					return err
+				}
+				for i36 := copy(z.Hash[:], x35); i36 < len(z.Hash); i36++ {
+					z.Hash[i36] = 0
+				}
+			}
+		case 11:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				if depth+1 == msgpack.MaxDepth {
+					return &msgpack.DepthError{Offset: c.Offset()}
+				}
+				n37, err := c.UnpackArrayHeader()
+				if err != nil {
+					return err
+				}
+				for i38 := 0; i38 < int(n37); i38++ {
+					if i38 >= len(z.Matrix) {
+						if err = c.Skip(); err != nil {
+							return err
+						}
+						continue
+					}
+					if c.IsNil() {
+						c.UnpackNil()
+					} else {
+						if depth+2 == msgpack.MaxDepth {
+							return &msgpack.DepthError{Offset: c.Offset()}
+						}
+						n39, err := c.UnpackArrayHeader()
+						if err != nil {
+							return err
+						}
+						for i40 := 0; i40 < int(n39); i40++ {
+							if i40 >= len(z.Matrix[i38]) {
+								if err = c.Skip(); err != nil {
+									return err
+								}
+								continue
+							}
+							if c.IsNil() {
+								c.UnpackNil()
+							} else {
+								x41, err := c.UnpackInt16()
+								if err != nil {
+									return err
+								}
+								z.Matrix[i38][i40] = x41
+							}
+						}
+						for i40 := int(n39); i40 < len(z.Matrix[i38]); i40++ {
+							var zero int16
+							z.Matrix[i38][i40] = zero
+						}
+					}
+				}
+				for i38 := int(n37); i38 < len(z.Matrix); i38++ {
+					var zero [2]int16
+					z.Matrix[i38] = zero
+				}
+			}
+		case 12:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x42, err := c.UnpackBool()
+				if err != nil {
+					return err
+				}
+				z.Paid = x42
+			}
+		case 13:
+			start43 := c.Offset()
+			if err = c.Skip(); err != nil {
+				return err
+			}
+			if err = msgpack.Unmarshal(b[start43:c.Offset()], &z.Extra); err != nil {
+				return err
+			}
+		case 14:
+			start44 := c.Offset()
+			if err = c.Skip(); err != nil {
+				return err
+			}
+			z.Meta = append(make(msgpack.RawMessage, 0, c.Offset()-start44), b[start44:c.Offset()]...)
+		case 15:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				code45, data46, err := c.UnpackExt()
+				if err != nil {
+					return err
+				}
+				z.Attachment = msgpack.Ext{Type: code45, Data: append(make([]byte, 0, len(data46)), data46...)}
+			}
+		default:
+			if err = c.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Msgsize returns an upper bound of the size of the encoding of z.
+func (z *Order) Msgsize() int {
+	s := 135
+	s += 5 + len(z.Customer)
+	s += 5
+	for i47 := range z.Items {
+		s += z.Items[i47].Msgsize()
+	}
+	if z.Gift == nil {
+		s++
+	} else {
+		s += z.Gift.Msgsize()
+	}
+	s += 5
+	for i48 := range z.Tags {
+		s += 5 + len(z.Tags[i48])
+	}
+	s += 5 + len(z.Totals)*5
+	for k49 := range z.Totals {
+		s += 5 + len(k49)
+	}
+	s += 5 + len(z.Counts)*9
+	for _, e52 := range z.Counts {
+		if e52 == nil {
+			s++
+		} else {
+			s += 9
+		}
+	}
+	s += 5 + len(z.Hash)
+	s += 5
+	for i53 := range z.Matrix {
+		s += 5 + len(z.Matrix[i53])*9
+	}
+	if x54, err := msgpack.Marshal(&z.Extra); err == nil {
+		s += len(x54)
+	}
+	s += 1 + len(z.Meta)
+	s += 6 + len(z.Attachment.Data)
+	return s
+}
+
+var msgpackFieldsItem = [...]string{
+	"sku",
+	"qty",
+	"price",
+	"data",
+}
+
+// MarshalMsg appends the MessagePack encoding of z to b, as msgpack.Marshal
+// would write it.
+func (z *Item) MarshalMsg(b []byte) ([]byte, error) {
+	n := uint32(4)
+	if z.Price == 0 {
+		n--
+	}
+	if len(z.Data) == 0 {
+		n--
+	}
+	b = msgpack.AppendMapHeader(b, n)
+	b = msgpack.AppendString(b, "sku")
+	b = msgpack.AppendString(b, z.SKU)
+	b = msgpack.AppendString(b, "qty")
+	b = msgpack.AppendUInt64(b, uint64(z.Quantity))
+	if z.Price != 0 {
+		b = msgpack.AppendString(b, "price")
+		b = msgpack.AppendDouble(b, z.Price)
+	}
+	if len(z.Data) != 0 {
+		b = msgpack.AppendString(b, "data")
+		b = msgpack.AppendBinary(b, z.Data)
+	}
+	return b, nil
+}
+
+// UnmarshalMsg decodes a value from the start of b into z, as
+// msgpack.Unmarshal would, and returns the bytes after it.
+func (z *Item) UnmarshalMsg(b []byte) ([]byte, error) {
+	c := msgpack.NewCursor(b)
+	if err := z.unmarshalMsg(b, c, 0); err != nil {
+		return b, err
+	}
+	return b[c.Offset():], nil
+}
+
+// unmarshalMsg decodes z inside depth arrays and maps.
+func (z *Item) unmarshalMsg(b []byte, c *msgpack.Cursor, depth int) error {
+	if c.IsNil() {
+		c.UnpackNil()
+		return nil
+	}
+	if depth == msgpack.MaxDepth {
+		return &msgpack.DepthError{Offset: c.Offset()}
+	}
+	n, err := c.UnpackMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		key, err := c.UnpackRawBuffer()
+		if err != nil {
+			return err
+		}
+		var f int
+		switch string(key) {
+		case "sku":
+			f = 1
+		case "qty":
+			f = 2
+		case "price":
+			f = 3
+		case "data":
+			f = 4
+		default:
+			for j, name := range msgpackFieldsItem {
+				if strings.EqualFold(name, string(key)) {
+					f = j + 1
+					break
+				}
+			}
+		}
+		switch f {
+		case 1:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x1, err := c.UnpackRawBuffer()
+				if err != nil {
+					return err
+				}
+				z.SKU = string(x1)
+			}
+		case 2:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x2, err := c.UnpackUInt16()
+				if err != nil {
+					return err
+				}
+				z.Quantity = x2
+			}
+		case 3:
+			if c.IsNil() {
+				c.UnpackNil()
+			} else {
+				x3, err := c.UnpackDouble()
+				if err != nil {
+					return err
+				}
+				z.Price = x3
+			}
+		case 4:
+			if c.IsNil() {
+				c.UnpackNil()
+				z.Data = nil
+			} else {
+				x4, err := c.UnpackRawBuffer()
+				if err != nil {
+					return err
+				}
+				z.Data = append(make([]byte, 0, len(x4)), x4...)
+			}
+		default:
+			if err = c.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Msgsize returns an upper bound of the size of the encoding of z.
+func (z *Item) Msgsize() int {
+	s := 42
+	s += 5 + len(z.SKU)
+	s += 5 + len(z.Data)
+	return s
+}
@@ -0,0 +1,181 @@
+// Code generated by msgpackgen. DO NOT EDIT.
+
+package example
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+func TestMarshalUnmarshalOrder(t *testing.T) {
+	v := msgpackTestOrder(0)
+	b, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if r, err := msgpack.Marshal(&v); err != nil || !bytes.Equal(b, r) {
+		t.Errorf("output differs from msgpack.Marshal: % x, % x, %v", b, r, err)
+	}
+	if v.Msgsize() < len(b) {
+		t.Error("Msgsize too small", v.Msgsize(), len(b))
+	}
+
+	var out Order
+	rest, err := out.UnmarshalMsg(append(b, 0xc0))
+	if err != nil || len(rest) != 1 {
+		t.Error("wrong output", rest, err)
+	}
+	if b2, err := out.MarshalMsg(nil); err != nil || !bytes.Equal(b, b2) {
+		t.Error("round trip differs", b2, err)
+	}
+
+	for i := 0; i < len(b); i++ {
+		if _, err := out.UnmarshalMsg(b[:i]); err == nil {
+			t.Error("expected error for truncated input", i)
+		}
+	}
+}
+
+func TestUnmarshalDepthOrder(t *testing.T) {
+	var v Order
+	var e *msgpack.DepthError
+	b := []byte{0x80}
+	if err := v.unmarshalMsg(b, msgpack.NewCursor(b), msgpack.MaxDepth); !errors.As(err, &e) || e.Offset != 0 {
+		t.Error("expected DepthError", err)
+	}
+	b = []byte{0xc0}
+	if err := v.unmarshalMsg(b, msgpack.NewCursor(b), msgpack.MaxDepth); err != nil {
+		t.Error("err != nil", err)
+	}
+}
+
+func BenchmarkMarshalMsgOrder(b *testing.B) {
+	v := msgpackTestOrder(0)
+	buf := make([]byte, 0, v.Msgsize())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = v.MarshalMsg(buf[:0])
+	}
+}
+
+func BenchmarkUnmarshalMsgOrder(b *testing.B) {
+	v := msgpackTestOrder(0)
+	buf, _ := v.MarshalMsg(nil)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		if _, err := v.UnmarshalMsg(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func msgpackTestOrder(depth int) Order {
+	var z Order
+	if depth == 3 {
+		return z
+	}
+	z.Base.ID = 7
+	z.Base.Created = time.Unix(1700000000, 5)
+	z.Customer = "x"
+	z.Status = -3
+	z.Items = []Item{msgpackTestItem(depth + 1)}
+	z.Gift = func() *Item {
+		var v Item = msgpackTestItem(depth + 1)
+		return &v
+	}()
+	z.Tags = Tags{"x"}
+	z.Totals = map[string]float32{"x": 1.5}
+	z.Counts = map[int]*uint{-3: func() *uint {
+		var v uint = 7
+		return &v
+	}()}
+	z.Hash = [4]byte{1}
+	z.Matrix = [2][2]int16{[2]int16{-3}}
+	z.Paid = true
+	z.Extra = "x"
+	z.Meta = msgpack.RawMessage{0x92, 0x01, 0xc3}
+	z.Attachment = msgpack.Ext{Type: 5, Data: []byte{1}}
+	return z
+}
+
+func TestMarshalUnmarshalItem(t *testing.T) {
+	v := msgpackTestItem(0)
+	b, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if r, err := msgpack.Marshal(&v); err != nil || !bytes.Equal(b, r) {
+		t.Errorf("output differs from msgpack.Marshal: % x, % x, %v", b, r, err)
+	}
+	if v.Msgsize() < len(b) {
+		t.Error("Msgsize too small", v.Msgsize(), len(b))
+	}
+
+	var out Item
+	rest, err := out.UnmarshalMsg(append(b, 0xc0))
+	if err != nil || len(rest) != 1 {
+		t.Error("wrong output", rest, err)
+	}
+	if b2, err := out.MarshalMsg(nil); err != nil || !bytes.Equal(b, b2) {
+		t.Error("round trip differs", b2, err)
+	}
+
+	for i := 0; i < len(b); i++ {
+		if _, err := out.UnmarshalMsg(b[:i]); err == nil {
+			t.Error("expected error for truncated input", i)
+		}
+	}
+}
+
+func TestUnmarshalDepthItem(t *testing.T) {
+	var v Item
+	var e *msgpack.DepthError
+	b := []byte{0x80}
+	if err := v.unmarshalMsg(b, msgpack.NewCursor(b), msgpack.MaxDepth); !errors.As(err, &e) || e.Offset != 0 {
+		t.Error("expected DepthError", err)
+	}
+	b = []byte{0xc0}
+	if err := v.unmarshalMsg(b, msgpack.NewCursor(b), msgpack.MaxDepth); err != nil {
+		t.Error("err != nil", err)
+	}
+}
+
+func BenchmarkMarshalMsgItem(b *testing.B) {
+	v := msgpackTestItem(0)
+	buf := make([]byte, 0, v.Msgsize())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = v.MarshalMsg(buf[:0])
+	}
+}
+
+func BenchmarkUnmarshalMsgItem(b *testing.B) {
+	v := msgpackTestItem(0)
+	buf, _ := v.MarshalMsg(nil)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		if _, err := v.UnmarshalMsg(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func msgpackTestItem(depth int) Item {
+	var z Item
+	if depth == 3 {
+		return z
+	}
+	z.SKU = "x"
+	z.Quantity = 7
+	z.Price = 1.5
+	z.Data = []byte{1, 2, 3}
+	return z
+}
@@ -0,0 +1,117 @@
+package example
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+func newOrder() Order {
+	qty := uint(3)
+	return Order{
+		Base:     Base{ID: 42, Created: time.Unix(1700000000, 500).UTC()},
+		Customer: "ann",
+		Status:   Shipped,
+		Items: []Item{
+			{SKU: "a-1", Quantity: 2, Price: 9.5},
+			{SKU: "b-2", Quantity: 300, Data: []byte{1, 2, 3}},
+		},
+		Gift:       &Item{SKU: "gift"},
+		Tags:       Tags{"rush", "fragile"},
+		Totals:     map[string]float32{"net": 19, "tax": 3.5, "gross": 22.5},
+		Counts:     map[int]*uint{-1: nil, 7: &qty, 2: &qty},
+		Hash:       [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Matrix:     [2][2]int16{{1, -2}, {-300, 4}},
+		Paid:       true,
+		Extra:      map[string]interface{}{"k": int64(-5)},
+		Meta:       msgpack.RawMessage{0x92, 0x01, 0xc3},
+		Attachment: msgpack.Ext{Type: 5, Data: []byte("hi")},
+		Note:       "not encoded",
+	}
+}
+
+func TestOrderMatchesReflection(t *testing.T) {
+	for _, v := range []Order{{}, newOrder()} {
+		b, err := v.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+
+		r, err := msgpack.Marshal(&v)
+		if err != nil || !bytes.Equal(b, r) {
+			t.Errorf("output differs from msgpack.Marshal:\n% x\n% x", b, r)
+		}
+		if v.Msgsize() < len(b) {
+			t.Error("Msgsize too small", v.Msgsize(), len(b))
+		}
+
+		var got, want Order
+		if rest, err := got.UnmarshalMsg(b); err != nil || len(rest) != 0 {
+			t.Fatal("wrong output", rest, err)
+		}
+		if err = msgpack.Unmarshal(b, &want); err != nil {
+			t.Fatal("err != nil", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decoded differently from msgpack.Unmarshal:\n%+v\n%+v", got, want)
+		}
+	}
+}
+
+func TestOrderUnmarshalLikeReflection(t *testing.T) {
+	for _, in := range []interface{}{
+		nil,
+		map[string]interface{}{"CUSTOMER": "bob", "unknown": []int{1, 2}, "status": 1.0},
+		map[string]interface{}{"items": nil, "gift": nil, "hash": "ab", "matrix": [][]int{{5}, {6, 7, 8}}},
+		map[string]interface{}{"totals": map[string]int{"x": 1}, "meta": nil, "extra": "s"},
+	} {
+		b, err := msgpack.Marshal(in)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+
+		got, want := newOrder(), newOrder()
+		if _, err = got.UnmarshalMsg(b); err != nil {
+			t.Fatal("err != nil", err)
+		}
+		if err = msgpack.Unmarshal(b, &want); err != nil {
+			t.Fatal("err != nil", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decoded differently from msgpack.Unmarshal for %v:\n%+v\n%+v", in, got, want)
+		}
+	}
+}
+
+func TestOrderUnmarshalErrors(t *testing.T) {
+	for _, in := range []interface{}{
+		[]int{1},
+		map[string]interface{}{"status": 300},
+		map[string]interface{}{"paid": "yes"},
+		map[int]int{1: 2},
+	} {
+		b, err := msgpack.Marshal(in)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+
+		var v Order
+		if _, err = v.UnmarshalMsg(b); err == nil {
+			t.Error("expected error for", in)
+		}
+		if err = msgpack.Unmarshal(b, &v); err == nil {
+			t.Error("expected error from msgpack.Unmarshal for", in)
+		}
+	}
+
+	b := msgpack.AppendMapHeader(nil, 1)
+	b = msgpack.AppendString(b, "items")
+	b = msgpack.AppendArrayHeader(b, 1000)
+	var v Order
+	if _, err := v.UnmarshalMsg(b); err == nil {
+		t.Error("expected error for huge array header")
+	}
+}
@@ -0,0 +1,818 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	msgpack "github.com/elisaday/msgpack-go"
+)
+
+const header = "// Code generated by msgpackgen. DO NOT EDIT.\n"
+
+type generator struct {
+	pkg     *pkgInfo
+	buf     bytes.Buffer
+	imports map[string]string
+	tmp     int
+	useErr  bool
+}
+
+// structTypes returns the struct types to generate: names, or every struct
+// in the package when names is empty, along with the structs they use.
+func (p *pkgInfo) structTypes(names []string) ([]string, map[string][]field, error) {
+	if len(names) == 0 {
+		for _, name := range p.order {
+			if p.decls[name].spec.TypeParams == nil && !p.hasCustom(name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	fields := map[string][]field{}
+	var out []string
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		if _, ok := fields[name]; ok {
+			continue
+		}
+
+		t, err := p.resolveName(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if t.kind != structKind {
+			return nil, nil, fmt.Errorf("%s is not a struct type the generator can handle", name)
+		}
+
+		f, err := p.structFields(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields[name] = f
+		out = append(out, name)
+
+		for _, f := range f {
+			names = append(names, usedStructs(f.typ)...)
+		}
+	}
+	return out, fields, nil
+}
+
+func (p *pkgInfo) resolveName(name string) (*typeInfo, error) {
+	if p.decls[name] == nil {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	return p.resolveNamed(name)
+}
+
+func usedStructs(t *typeInfo) []string {
+	switch {
+	case t == nil:
+		return nil
+	case t.kind == structKind:
+		return []string{t.name}
+	}
+	return append(usedStructs(t.key), usedStructs(t.elem)...)
+}
+
+// generate returns the source of the methods for the struct types names
+// and, if tests is set, of their tests.
+func generate(p *pkgInfo, names []string, tests bool) (code, test []byte, err error) {
+	names, fields, err := p.structTypes(names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := &generator{pkg: p, imports: map[string]string{"msgpack": msgpackPath}}
+	for _, name := range names {
+		g.structMethods(name, fields[name])
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "\npackage %s\n\nimport (\n", p.name)
+	writeImports(&buf, g.imports)
+	buf.WriteString(")\n")
+	buf.Write(g.buf.Bytes())
+
+	if code, err = format.Source(buf.Bytes()); err != nil {
+		return nil, nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	if !tests {
+		return code, nil, nil
+	}
+
+	g = &generator{pkg: p, imports: map[string]string{"bytes": "bytes", "errors": "errors", "testing": "testing", "msgpack": msgpackPath}}
+	for _, name := range names {
+		writeTests(&g.buf, name)
+		g.testValue(name, fields[name])
+	}
+
+	buf.Reset()
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "\npackage %s\n\nimport (\n", p.name)
+	writeImports(&buf, g.imports)
+	buf.WriteString(")\n")
+	buf.Write(g.buf.Bytes())
+
+	if test, err = format.Source(buf.Bytes()); err != nil {
+		return nil, nil, fmt.Errorf("formatting generated tests: %v", err)
+	}
+	return code, test, nil
+}
+
+func writeImports(buf *bytes.Buffer, imports map[string]string) {
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := imports[names[i]], imports[names[j]]
+		if strings.Contains(a, ".") != strings.Contains(b, ".") {
+			return !strings.Contains(a, ".")
+		}
+		return a < b
+	})
+
+	std := true
+	for _, name := range names {
+		path := imports[name]
+		if std && strings.Contains(path, ".") {
+			std = false
+			buf.WriteByte('\n')
+		}
+		if name == path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(buf, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(buf, "\t%s %q\n", name, path)
+		}
+	}
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) temp(prefix string) string {
+	g.tmp++
+	return prefix + strconv.Itoa(g.tmp)
+}
+
+// typeName spells t in the generated code, importing what it needs.
+func (g *generator) typeName(t *typeInfo) string {
+	for name, path := range t.imports {
+		g.imports[name] = path
+	}
+	for e := t.elem; e != nil; e = e.elem {
+		for name, path := range e.imports {
+			g.imports[name] = path
+		}
+	}
+	return t.name
+}
+
+func (g *generator) structMethods(name string, fields []field) {
+	g.tmp = 0
+	g.p("\nvar msgpackFields%s = [...]string{", name)
+	for _, f := range fields {
+		g.p("%q,", f.name)
+	}
+	g.p("}")
+
+	g.marshal(name, fields)
+	g.unmarshal(name, fields)
+	g.msgsize(name, fields)
+}
+
+func fieldExpr(f field) string {
+	return "z." + strings.Join(f.path, ".")
+}
+
+func (g *generator) marshal(name string, fields []field) {
+	var body bytes.Buffer
+	g.buf, body = body, g.buf
+	g.useErr = false
+
+	n := "uint32(" + strconv.Itoa(len(fields)) + ")"
+	var omit bool
+	for _, f := range fields {
+		if f.omitEmpty && emptyCheck(fieldExpr(f), f.typ, false) != "" {
+			omit = true
+		}
+	}
+	if omit {
+		g.p("n := %s", n)
+		for _, f := range fields {
+			if check := emptyCheck(fieldExpr(f), f.typ, false); f.omitEmpty && check != "" {
+				g.p("if %s {\nn--\n}", check)
+			}
+		}
+		n = "n"
+	}
+	g.p("b = msgpack.AppendMapHeader(b, %s)", n)
+
+	for _, f := range fields {
+		v := fieldExpr(f)
+		check := emptyCheck(v, f.typ, true)
+		if !f.omitEmpty || check == "" {
+			g.p("b = msgpack.AppendString(b, %q)", f.name)
+			g.encode(v, f.typ, false)
+			continue
+		}
+		g.p("if %s {", check)
+		g.p("b = msgpack.AppendString(b, %q)", f.name)
+		g.encode(v, f.typ, true)
+		g.p("}")
+	}
+
+	g.buf, body = body, g.buf
+	g.p("\n// MarshalMsg appends the MessagePack encoding of z to b, as msgpack.Marshal\n// would write it.")
+	g.p("func (z *%s) MarshalMsg(b []byte) ([]byte, error) {", name)
+	if g.useErr {
+		g.p("var err error")
+	}
+	g.buf.Write(body.Bytes())
+	g.p("return b, nil\n}")
+}
+
+// emptyCheck returns the condition under which omitempty leaves out v, or
+// "" for types that are never empty. With set it returns the opposite.
+func emptyCheck(v string, t *typeInfo, set bool) string {
+	eq, not := " == ", "!"
+	if set {
+		eq, not = " != ", ""
+	}
+
+	switch t.kind {
+	case basicKind:
+		switch t.basic {
+		case "bool":
+			return not + v
+		case "string":
+			return "len(" + v + ")" + eq + "0"
+		}
+		return v + eq + "0"
+	case bytesKind, byteArrayKind, sliceKind, arrayKind, mapKind, rawKind:
+		return "len(" + v + ")" + eq + "0"
+	case ptrKind:
+		return v + eq + "nil"
+	case fallbackKind:
+		if t.iface {
+			return v + eq + "nil"
+		}
+	}
+	return ""
+}
+
+// deref spells *v so that it can be used as an operand.
+func deref(v string, t *typeInfo) string {
+	if t.kind == basicKind || t.kind == bytesKind || t.kind == timeKind || t.kind == rawKind {
+		return "*" + v
+	}
+	return "(*" + v + ")"
+}
+
+func addr(v string) string {
+	if strings.HasPrefix(v, "(*") && strings.HasSuffix(v, ")") {
+		return v[2 : len(v)-1]
+	}
+	if strings.HasPrefix(v, "*") {
+		return v[1:]
+	}
+	return "&" + v
+}
+
+// convert spells v, of type t, as the predeclared type to.
+func convert(to, v string, t *typeInfo) string {
+	if t.name == to || t.name == "byte" && to == "uint8" || t.name == "rune" && to == "int32" {
+		return v
+	}
+	return to + "(" + v + ")"
+}
+
+func isInt(basic string) bool {
+	return strings.HasPrefix(basic, "int")
+}
+
+func isUint(basic string) bool {
+	return strings.HasPrefix(basic, "uint")
+}
+
+// encode appends v. With notNil set v is known not to be nil.
+func (g *generator) encode(v string, t *typeInfo, notNil bool) {
+	switch t.kind {
+	case basicKind:
+		switch b := t.basic; {
+		case b == "bool":
+			g.p("b = msgpack.AppendBool(b, %s)", convert("bool", v, t))
+		case isInt(b):
+			g.p("b = msgpack.AppendInt64(b, %s)", convert("int64", v, t))
+		case isUint(b):
+			g.p("b = msgpack.AppendUInt64(b, %s)", convert("uint64", v, t))
+		case b == "float32":
+			g.p("b = msgpack.AppendFloat(b, %s)", convert("float32", v, t))
+		case b == "float64":
+			g.p("b = msgpack.AppendDouble(b, %s)", convert("float64", v, t))
+		case b == "string":
+			g.p("b = msgpack.AppendString(b, %s)", convert("string", v, t))
+		}
+	case bytesKind:
+		if !notNil {
+			g.p("if %s == nil {\nb = msgpack.AppendNil(b)\n} else {", v)
+			defer g.p("}")
+		}
+		g.p("b = msgpack.AppendBinary(b, %s)", v)
+	case byteArrayKind:
+		g.p("b = msgpack.AppendBinary(b, %s[:])", v)
+	case sliceKind, arrayKind:
+		if t.kind == sliceKind && !notNil {
+			g.p("if %s == nil {\nb = msgpack.AppendNil(b)\n} else {", v)
+			defer g.p("}")
+		}
+		i := g.temp("i")
+		g.p("b = msgpack.AppendArrayHeader(b, uint32(len(%s)))", v)
+		g.p("for %s := range %s {", i, v)
+		g.encode(v+"["+i+"]", t.elem, false)
+		g.p("}")
+	case mapKind:
+		if !notNil {
+			g.p("if %s == nil {\nb = msgpack.AppendNil(b)\n} else {", v)
+			defer g.p("}")
+		}
+		g.p("b = msgpack.AppendMapHeader(b, uint32(len(%s)))", v)
+		k, e := g.temp("k"), g.temp("e")
+		if t.key.basic == "bool" {
+			g.p("for %s, %s := range %s {", k, e, v)
+		} else {
+			keys := g.temp("keys")
+			g.imports["sort"] = "sort"
+			g.p("%s := make([]%s, 0, len(%s))", keys, g.typeName(t.key), v)
+			g.p("for %s := range %s {\n%s = append(%s, %s)\n}", k, v, keys, keys, k)
+			g.p("sort.Slice(%s, func(i, j int) bool { return %s[i] < %s[j] })", keys, keys, keys)
+			g.p("for _, %s := range %s {", k, keys)
+			g.p("%s := %s[%s]", e, v, k)
+		}
+		g.encode(k, t.key, false)
+		g.encode(e, t.elem, false)
+		g.p("}")
+	case ptrKind:
+		if !notNil {
+			g.p("if %s == nil {\nb = msgpack.AppendNil(b)\n} else {", v)
+			defer g.p("}")
+		}
+		if t.elem.kind == structKind {
+			g.encode(v, t.elem, false)
+		} else {
+			g.encode(deref(v, t.elem), t.elem, false)
+		}
+	case structKind:
+		g.useErr = true
+		g.p("if b, err = %s.MarshalMsg(b); err != nil {\nreturn b, err\n}", v)
+	case timeKind:
+		g.p("b = msgpack.AppendTime(b, %s)", v)
+	case extKind:
+		g.p("b = msgpack.AppendExt(b, %s.Type, %s.Data)", v, v)
+	case rawKind:
+		if !notNil {
+			g.p("if len(%s) == 0 {\nb = msgpack.AppendNil(b)\n} else {", v)
+			defer g.p("}")
+		}
+		g.p("b = append(b, %s...)", v)
+	case fallbackKind:
+		x := g.temp("x")
+		g.p("%s, err := msgpack.Marshal(%s)", x, addr(v))
+		g.p("if err != nil {\nreturn b, err\n}")
+		g.p("b = append(b, %s...)", x)
+	}
+}
+
+func (g *generator) unmarshal(name string, fields []field) {
+	g.p("\n// UnmarshalMsg decodes a value from the start of b into z, as\n// msgpack.Unmarshal would, and returns the bytes after it.")
+	g.p("func (z *%s) UnmarshalMsg(b []byte) ([]byte, error) {", name)
+	g.p("c := msgpack.NewCursor(b)")
+	g.p("if err := z.unmarshalMsg(b, c, 0); err != nil {\nreturn b, err\n}")
+	g.p("return b[c.Offset():], nil\n}")
+
+	g.p("\n// unmarshalMsg decodes z inside depth arrays and maps.")
+	g.p("func (z *%s) unmarshalMsg(b []byte, c *msgpack.Cursor, depth int) error {", name)
+	g.p("if c.IsNil() {\nc.UnpackNil()\nreturn nil\n}")
+	g.checkDepth(0)
+	g.p("n, err := c.UnpackMapHeader()\nif err != nil {\nreturn err\n}")
+	g.p("for i := uint32(0); i < n; i++ {")
+
+	if len(fields) == 0 {
+		g.p("if _, err = c.UnpackRawBuffer(); err != nil {\nreturn err\n}")
+		g.p("if err = c.Skip(); err != nil {\nreturn err\n}\n}\nreturn nil\n}")
+		return
+	}
+
+	g.p("key, err := c.UnpackRawBuffer()\nif err != nil {\nreturn err\n}")
+
+	g.p("var f int\nswitch string(key) {")
+	for i, f := range fields {
+		g.p("case %q:\nf = %d", f.name, i+1)
+	}
+	g.p("default:")
+	g.p("for j, name := range msgpackFields%s {", name)
+	g.p("if strings.EqualFold(name, string(key)) {\nf = j + 1\nbreak\n}\n}\n}")
+	g.imports["strings"] = "strings"
+
+	g.p("switch f {")
+	for i, f := range fields {
+		g.p("case %d:", i+1)
+		g.decode(fieldExpr(f), f.typ, false, 1)
+	}
+	g.p("default:\nif err = c.Skip(); err != nil {\nreturn err\n}\n}\n}")
+	g.p("return nil\n}")
+}
+
+var unpackFuncs = map[string]string{
+	"bool":    "UnpackBool",
+	"int":     "UnpackInt",
+	"int8":    "UnpackInt8",
+	"int16":   "UnpackInt16",
+	"int32":   "UnpackInt32",
+	"int64":   "UnpackInt64",
+	"uint":    "UnpackUInt",
+	"uint8":   "UnpackUInt8",
+	"uint16":  "UnpackUInt16",
+	"uint32":  "UnpackUInt32",
+	"uint64":  "UnpackUInt64",
+	"uintptr": "UnpackUInt64",
+	"float32": "UnpackFloat",
+	"float64": "UnpackDouble",
+	"string":  "UnpackRawBuffer",
+}
+
+// depthExpr spells the depth of a value nested level arrays and maps below
+// the struct being decoded.
+func depthExpr(level int) string {
+	if level == 0 {
+		return "depth"
+	}
+	return "depth+" + strconv.Itoa(level)
+}
+
+// checkDepth fails like msgpack.Unmarshal when the array or map at the
+// cursor, level arrays and maps inside the struct, nests too deeply.
+func (g *generator) checkDepth(level int) {
+	g.p("if %s == msgpack.MaxDepth {\nreturn &msgpack.DepthError{Offset: c.Offset()}\n}", depthExpr(level))
+}
+
+// decode reads a value into v, level arrays and maps inside the struct being
+// decoded. Like the reflection decoder it leaves v alone when it meets nil,
+// unless v is a pointer, slice or map.
+func (g *generator) decode(v string, t *typeInfo, notNil bool, level int) {
+	switch t.kind {
+	case structKind:
+		g.p("if err = %s.unmarshalMsg(b, c, %s); err != nil {\nreturn err\n}", v, depthExpr(level))
+		return
+	case rawKind:
+		start := g.temp("start")
+		g.p("%s := c.Offset()", start)
+		g.p("if err = c.Skip(); err != nil {\nreturn err\n}")
+		g.p("%s = append(make(%s, 0, c.Offset()-%s), b[%s:c.Offset()]...)", v, g.typeName(t), start, start)
+		return
+	case fallbackKind:
+		start := g.temp("start")
+		g.p("%s := c.Offset()", start)
+		g.p("if err = c.Skip(); err != nil {\nreturn err\n}")
+		g.p("if err = msgpack.Unmarshal(b[%s:c.Offset()], %s); err != nil {\nreturn err\n}", start, addr(v))
+		return
+	}
+
+	if !notNil {
+		g.p("if c.IsNil() {\nc.UnpackNil()")
+		switch t.kind {
+		case ptrKind, bytesKind, sliceKind, mapKind:
+			g.p("%s = nil", v)
+		}
+		g.p("} else {")
+	}
+
+	switch t.kind {
+	case basicKind:
+		x := g.temp("x")
+		g.p("%s, err := c.%s()\nif err != nil {\nreturn err\n}", x, unpackFuncs[t.basic])
+		switch {
+		case t.basic == "string", t.basic == "uintptr":
+			g.p("%s = %s(%s)", v, t.name, x)
+		case convert(t.basic, x, t) != x:
+			g.p("%s = %s(%s)", v, t.name, x)
+		default:
+			g.p("%s = %s", v, x)
+		}
+	case bytesKind:
+		x := g.temp("x")
+		g.p("%s, err := c.UnpackRawBuffer()\nif err != nil {\nreturn err\n}", x)
+		g.p("%s = append(make(%s, 0, len(%s)), %s...)", v, t.name, x, x)
+	case byteArrayKind:
+		x, i := g.temp("x"), g.temp("i")
+		g.p("%s, err := c.UnpackRawBuffer()\nif err != nil {\nreturn err\n}", x)
+		g.p("for %s := copy(%s[:], %s); %s < len(%s); %s++ {\n%s[%s] = 0\n}", i, v, x, i, v, i, v, i)
+	case sliceKind:
+		n, s, i := g.temp("n"), g.temp("s"), g.temp("i")
+		g.checkDepth(level)
+		g.p("%s, err := c.UnpackArrayHeader()\nif err != nil {\nreturn err\n}", n)
+		g.p("if uint64(%s) > uint64(c.Len()) {\nreturn msgpack.ErrUnpackOverflow\n}", n)
+		g.p("%s := make(%s, %s)", s, g.typeName(t), n)
+		g.p("for %s := range %s {", i, s)
+		g.decode(s+"["+i+"]", t.elem, false, level+1)
+		g.p("}")
+		g.p("%s = %s", v, s)
+	case arrayKind:
+		n, i := g.temp("n"), g.temp("i")
+		g.checkDepth(level)
+		g.p("%s, err := c.UnpackArrayHeader()\nif err != nil {\nreturn err\n}", n)
+		g.p("for %s := 0; %s < int(%s); %s++ {", i, i, n, i)
+		g.p("if %s >= len(%s) {\nif err = c.Skip(); err != nil {\nreturn err\n}\ncontinue\n}", i, v)
+		g.decode(v+"["+i+"]", t.elem, false, level+1)
+		g.p("}")
+		g.p("for %s := int(%s); %s < len(%s); %s++ {", i, n, i, v, i)
+		g.p("var zero %s\n%s[%s] = zero\n}", g.typeName(t.elem), v, i)
+	case mapKind:
+		n, i, k, e := g.temp("n"), g.temp("i"), g.temp("k"), g.temp("e")
+		g.checkDepth(level)
+		g.p("%s, err := c.UnpackMapHeader()\nif err != nil {\nreturn err\n}", n)
+		g.p("if uint64(%s) > uint64(c.Len()) {\nreturn msgpack.ErrUnpackOverflow\n}", n)
+		g.p("if %s == nil {\n%s = make(%s, %s)\n}", v, v, g.typeName(t), n)
+		g.p("for %s := uint32(0); %s < %s; %s++ {", i, i, n, i)
+		g.p("var %s %s", k, g.typeName(t.key))
+		g.decode(k, t.key, false, level+1)
+		g.p("var %s %s", e, g.typeName(t.elem))
+		g.decode(e, t.elem, false, level+1)
+		g.p("%s[%s] = %s\n}", v, k, e)
+	case ptrKind:
+		g.p("if %s == nil {\n%s = new(%s)\n}", v, v, g.typeName(t.elem))
+		if t.elem.kind == structKind {
+			g.decode(v, t.elem, true, level)
+		} else {
+			g.decode(deref(v, t.elem), t.elem, true, level)
+		}
+	case timeKind:
+		x := g.temp("x")
+		g.p("%s, err := c.UnpackTime()\nif err != nil {\nreturn err\n}", x)
+		g.p("%s = %s", v, x)
+	case extKind:
+		code, data := g.temp("code"), g.temp("data")
+		g.p("%s, %s, err := c.UnpackExt()\nif err != nil {\nreturn err\n}", code, data)
+		g.p("%s = msgpack.Ext{Type: %s, Data: append(make([]byte, 0, len(%s)), %s...)}", v, code, data, data)
+	}
+
+	if !notNil {
+		g.p("}")
+	}
+}
+
+// fixedSize returns the largest encoding of t if it does not depend on the
+// value.
+func fixedSize(t *typeInfo) (int, bool) {
+	switch t.kind {
+	case basicKind:
+		switch t.basic {
+		case "bool":
+			return 1, true
+		case "float32":
+			return 5, true
+		case "string":
+			return 0, false
+		}
+		return 9, true
+	case timeKind:
+		return 15, true
+	}
+	return 0, false
+}
+
+func (g *generator) msgsize(name string, fields []field) {
+	var body bytes.Buffer
+	g.buf, body = body, g.buf
+
+	total := 5
+	for _, f := range fields {
+		total += len(msgpack.AppendString(nil, f.name))
+		if n, ok := fixedSize(f.typ); ok {
+			total += n
+		} else {
+			g.size(fieldExpr(f), f.typ)
+		}
+	}
+
+	g.buf, body = body, g.buf
+	g.p("\n// Msgsize returns an upper bound of the size of the encoding of z.")
+	g.p("func (z *%s) Msgsize() int {", name)
+	g.p("s := %d", total)
+	g.buf.Write(body.Bytes())
+	g.p("return s\n}")
+}
+
+func (g *generator) size(v string, t *typeInfo) {
+	if n, ok := fixedSize(t); ok {
+		g.p("s += %d", n)
+		return
+	}
+
+	switch t.kind {
+	case basicKind, bytesKind, byteArrayKind:
+		g.p("s += 5 + len(%s)", v)
+	case rawKind:
+		g.p("s += 1 + len(%s)", v)
+	case extKind:
+		g.p("s += 6 + len(%s.Data)", v)
+	case sliceKind, arrayKind:
+		if n, ok := fixedSize(t.elem); ok {
+			g.p("s += 5 + len(%s)*%d", v, n)
+			return
+		}
+		i := g.temp("i")
+		g.p("s += 5\nfor %s := range %s {", i, v)
+		g.size(v+"["+i+"]", t.elem)
+		g.p("}")
+	case mapKind:
+		kn, kok := fixedSize(t.key)
+		en, eok := fixedSize(t.elem)
+		if kok && eok {
+			g.p("s += 5 + len(%s)*%d", v, kn+en)
+			return
+		}
+		k, e := g.temp("k"), g.temp("e")
+		switch {
+		case kok:
+			g.p("s += 5 + len(%s)*%d\nfor _, %s := range %s {", v, kn, e, v)
+			g.size(e, t.elem)
+		case eok:
+			g.p("s += 5 + len(%s)*%d\nfor %s := range %s {", v, en, k, v)
+			g.size(k, t.key)
+		default:
+			g.p("s += 5\nfor %s, %s := range %s {", k, e, v)
+			g.size(k, t.key)
+			g.size(e, t.elem)
+		}
+		g.p("}")
+	case ptrKind:
+		g.p("if %s == nil {\ns++\n} else {", v)
+		if t.elem.kind == structKind {
+			g.size(v, t.elem)
+		} else {
+			g.size(deref(v, t.elem), t.elem)
+		}
+		g.p("}")
+	case structKind:
+		g.p("s += %s.Msgsize()", v)
+	case fallbackKind:
+		x := g.temp("x")
+		g.p("if %s, err := msgpack.Marshal(%s); err == nil {\ns += len(%s)\n}", x, addr(v), x)
+	}
+}
+
+func writeTests(buf *bytes.Buffer, name string) {
+	fmt.Fprintf(buf, `
+func TestMarshalUnmarshal%[1]s(t *testing.T) {
+	v := msgpackTest%[1]s(0)
+	b, err := v.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if r, err := msgpack.Marshal(&v); err != nil || !bytes.Equal(b, r) {
+		t.Errorf("output differs from msgpack.Marshal: %% x, %% x, %%v", b, r, err)
+	}
+	if v.Msgsize() < len(b) {
+		t.Error("Msgsize too small", v.Msgsize(), len(b))
+	}
+
+	var out %[1]s
+	rest, err := out.UnmarshalMsg(append(b, 0xc0))
+	if err != nil || len(rest) != 1 {
+		t.Error("wrong output", rest, err)
+	}
+	if b2, err := out.MarshalMsg(nil); err != nil || !bytes.Equal(b, b2) {
+		t.Error("round trip differs", b2, err)
+	}
+
+	for i := 0; i < len(b); i++ {
+		if _, err := out.UnmarshalMsg(b[:i]); err == nil {
+			t.Error("expected error for truncated input", i)
+		}
+	}
+}
+
+func TestUnmarshalDepth%[1]s(t *testing.T) {
+	var v %[1]s
+	var e *msgpack.DepthError
+	b := []byte{0x80}
+	if err := v.unmarshalMsg(b, msgpack.NewCursor(b), msgpack.MaxDepth); !errors.As(err, &e) || e.Offset != 0 {
+		t.Error("expected DepthError", err)
+	}
+	b = []byte{0xc0}
+	if err := v.unmarshalMsg(b, msgpack.NewCursor(b), msgpack.MaxDepth); err != nil {
+		t.Error("err != nil", err)
+	}
+}
+
+func BenchmarkMarshalMsg%[1]s(b *testing.B) {
+	v := msgpackTest%[1]s(0)
+	buf := make([]byte, 0, v.Msgsize())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = v.MarshalMsg(buf[:0])
+	}
+}
+
+func BenchmarkUnmarshalMsg%[1]s(b *testing.B) {
+	v := msgpackTest%[1]s(0)
+	buf, _ := v.MarshalMsg(nil)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		if _, err := v.UnmarshalMsg(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+`, name)
+}
+
+// maxTestDepth is how deeply the values of the generated tests nest
+// structs, which may refer to themselves through pointers, slices and
+// maps.
+const maxTestDepth = 3
+
+// testValue writes a function returning a value of the struct type name
+// with every field set, and containers and pointers not nil, so that the
+// generated tests compare real encodings with msgpack.Marshal.
+func (g *generator) testValue(name string, fields []field) {
+	g.p("\nfunc msgpackTest%s(depth int) %s {", name, name)
+	g.p("var z %s\nif depth == %d {\nreturn z\n}", name, maxTestDepth)
+	for _, f := range fields {
+		if v := g.sample(f.typ); v != "" {
+			g.p("%s = %s", fieldExpr(f), v)
+		}
+	}
+	g.p("return z\n}")
+}
+
+// sample spells a value of type t that is not empty, or returns "" for
+// types the generator knows nothing about. Basic values are untyped
+// constants.
+func (g *generator) sample(t *typeInfo) string {
+	name := g.typeName(t)
+	switch t.kind {
+	case basicKind:
+		switch b := t.basic; {
+		case b == "bool":
+			return "true"
+		case b == "string":
+			return "\"x\""
+		case isInt(b):
+			return "-3"
+		case isUint(b):
+			return "7"
+		}
+		return "1.5"
+	case bytesKind:
+		return name + "{1, 2, 3}"
+	case byteArrayKind:
+		if t.length == "0" {
+			return name + "{}"
+		}
+		return name + "{1}"
+	case sliceKind, arrayKind:
+		elem := g.sample(t.elem)
+		if elem == "" || t.length == "0" {
+			return name + "{}"
+		}
+		return name + "{" + elem + "}"
+	case mapKind:
+		elem := g.sample(t.elem)
+		if elem == "" {
+			return name + "{}"
+		}
+		return name + "{" + g.sample(t.key) + ": " + elem + "}"
+	case ptrKind:
+		elem := g.sample(t.elem)
+		if elem == "" {
+			return "new(" + g.typeName(t.elem) + ")"
+		}
+		return "func() " + name + " {\nvar v " + g.typeName(t.elem) + " = " + elem + "\nreturn &v\n}()"
+	case structKind:
+		return "msgpackTest" + name + "(depth + 1)"
+	case timeKind:
+		return name[:strings.LastIndex(name, ".")] + ".Unix(1700000000, 5)"
+	case extKind:
+		return name + "{Type: 5, Data: []byte{1}}"
+	case rawKind:
+		return name + "{0x92, 0x01, 0xc3}"
+	case fallbackKind:
+		if name == "any" || name == "interface{}" {
+			return "\"x\""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExample checks that the generated files of the example package are
+// what the generator writes now. Run go generate in example after changing
+// the generator.
+func TestExample(t *testing.T) {
+	p, err := loadPackage("example")
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	code, test, err := generate(p, []string{"Order"}, true)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	for name, want := range map[string][]byte{
+		"example_msgpack.go":      code,
+		"example_msgpack_test.go": test,
+	} {
+		got, err := os.ReadFile(filepath.Join("example", name))
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run go generate in example", name)
+		}
+	}
+}
+
+func writePackage(t *testing.T, src string) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644); err != nil {
+		t.Fatal("err != nil", err)
+	}
+	return dir
+}
+
+func TestGenerateFields(t *testing.T) {
+	dir := writePackage(t, `package p
+
+type inner struct {
+	A int
+	B int `+"`msgpack:\"b\"`"+`
+}
+
+type Other struct {
+	A string
+}
+
+type T struct {
+	inner
+	Other
+	B     string `+"`json:\"b\"`"+`
+	C, D  bool
+	e     int
+	Skip  int `+"`msgpack:\"-\"`"+`
+}
+`)
+
+	p, err := loadPackage(dir)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	fields, err := p.structFields("T")
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.name+"="+fieldExpr(f))
+	}
+	if s := strings.Join(names, " "); s != "b=z.B C=z.C D=z.D" {
+		t.Error("wrong output", s)
+	}
+}
+
+func TestGenerateFieldsEmbeddedTwice(t *testing.T) {
+	dir := writePackage(t, `package p
+
+type C struct{ X int }
+type A struct{ C }
+type B struct{ C }
+
+type T struct {
+	A
+	B
+	Y int
+}
+`)
+
+	p, err := loadPackage(dir)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	// X is reached through both A and B at the same depth, so it is
+	// ambiguous and dropped, as in the reflection codec.
+	fields, err := p.structFields("T")
+	if err != nil || len(fields) != 1 || fields[0].name != "Y" {
+		t.Error("wrong output", fields, err)
+	}
+}
+
+func TestGenerateTestValues(t *testing.T) {
+	dir := writePackage(t, `package p
+
+import "bytes"
+
+type Node struct {
+	Next *Node
+	Kids []Node
+	Ints *[]uint8
+	Buf  bytes.Buffer
+	Any  interface{}
+}
+`)
+
+	p, err := loadPackage(dir)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+	_, test, err := generate(p, nil, true)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	// Nodes nest until maxTestDepth and fields of unknown types keep their
+	// zero value.
+	for _, want := range []string{
+		"if depth == 3 {",
+		"var v Node = msgpackTestNode(depth + 1)",
+		"z.Kids = []Node{msgpackTestNode(depth + 1)}",
+		"var v []uint8 = []uint8{1, 2, 3}",
+		"z.Any = \"x\"",
+	} {
+		if !bytes.Contains(test, []byte(want)) {
+			t.Errorf("missing %q in\n%s", want, test)
+		}
+	}
+	if bytes.Contains(test, []byte("z.Buf")) {
+		t.Error("unexpected value for z.Buf")
+	}
+}
+
+func TestGenerateDepth(t *testing.T) {
+	dir := writePackage(t, `package p
+
+type Node struct {
+	Next *Node
+	Kids map[string][]Node
+}
+`)
+
+	p, err := loadPackage(dir)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+	code, _, err := generate(p, nil, false)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	// Every struct, array and map checks its depth, counted like
+	// msgpack.Unmarshal counts it, before reading its header.
+	for _, want := range []string{
+		"if err := z.unmarshalMsg(b, c, 0); err != nil {",
+		"if depth == msgpack.MaxDepth {",
+		"if err = z.Next.unmarshalMsg(b, c, depth+1); err != nil {",
+		"if depth+1 == msgpack.MaxDepth {",
+		"if depth+2 == msgpack.MaxDepth {",
+		"unmarshalMsg(b, c, depth+3); err != nil {",
+	} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("missing %q in\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, c := range []struct {
+		src, typ, err string
+	}{
+		{"type T struct{ C chan int }", "T", "unsupported type chan int"},
+		{"type T struct{ *U }\ntype U struct{}", "T", "embedded pointer *U"},
+		{"import \"sync\"\ntype T struct{ sync.Mutex }", "T", "embedded sync.Mutex"},
+		{"import \"bytes\"\ntype T struct{ B bytes.Buffer `msgpack:\",omitempty\"` }", "T", "omitempty is not supported"},
+		{"type T int", "T", "not a struct type"},
+		{"type T[X any] struct{ V X }", "T", "generic type T"},
+		{"type T struct{}", "U", "type U not found"},
+		{"type L []L\ntype T struct{ L L }", "T", "recursive type L"},
+		{"type T struct{}\nfunc (T) MarshalText() ([]byte, error) { return nil, nil }", "T", "not a struct type"},
+	} {
+		p, err := loadPackage(writePackage(t, "package p\n"+c.src+"\n"))
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+		if _, _, err = generate(p, []string{c.typ}, false); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("wrong error for %q: %v", c.src, err)
+		}
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	dir := writePackage(t, "package p\n\ntype T struct{ A int }\n")
+
+	if err := run(dir, "", "", true); err != nil {
+		t.Fatal("err != nil", err)
+	}
+	for _, name := range []string{"p_msgpack.go", "p_msgpack_test.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error("missing output", name, err)
+		}
+	}
+
+	// The output of an earlier run is not read as input.
+	if err := run(dir, "T", "", false); err != nil {
+		t.Error("err != nil", err)
+	}
+
+	if err := run(dir, "T", filepath.Join(dir, "p.go"), false); err == nil {
+		t.Error("expected error overwriting a source file")
+	}
+}
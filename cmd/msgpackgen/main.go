@@ -0,0 +1,99 @@
+// Command msgpackgen writes MarshalMsg, UnmarshalMsg and Msgsize methods
+// for struct types, calling the Append* functions and Cursor methods of
+// msgpack directly instead of going through reflection.
+//
+// Usage:
+//
+//	msgpackgen [-type T1,T2] [-o file] [-tests=false] [dir]
+//
+// It reads the package in dir, the current directory by default, which
+// makes it easy to run from a go:generate comment:
+//
+//	//go:generate msgpackgen -type Order
+//
+// Without -type it handles every struct type in the package. Struct types
+// used by the listed ones get methods too. The methods go to
+// <package>_msgpack.go unless -o says otherwise, and tests checking each
+// type against the reflection codec go next to them in a _test.go file.
+//
+// The generated code reads the same struct tags and writes the same bytes
+// as msgpack.Marshal does for a pointer to the value. UnmarshalMsg decodes
+// like msgpack.Unmarshal but returns the bytes following the value instead
+// of failing on them. Fields whose type comes from another package, other
+// than time.Time, msgpack.Ext and msgpack.RawMessage, interfaces and types
+// with their own MarshalMsgpack, MarshalBinary or similar methods are
+// handed to msgpack.Marshal and msgpack.Unmarshal. Types added to an ext
+// registry are not known to the generator and are encoded by their kind.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct types; all struct types if empty")
+	output := flag.String("o", "", "output file; default <package>_msgpack.go in the package directory")
+	tests := flag.Bool("tests", true, "also write tests for the generated methods")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: msgpackgen [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(dir, *typeNames, *output, *tests); err != nil {
+		fmt.Fprintln(os.Stderr, "msgpackgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, typeNames, output string, tests bool) error {
+	p, err := loadPackage(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if typeNames != "" {
+		names = strings.Split(typeNames, ",")
+	}
+
+	code, test, err := generate(p, names, tests)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = filepath.Join(dir, p.name+"_msgpack.go")
+	}
+	if err = writeFile(output, code); err != nil {
+		return err
+	}
+	if test != nil {
+		err = writeFile(strings.TrimSuffix(output, ".go")+"_test.go", test)
+	}
+	return err
+}
+
+// writeFile refuses to overwrite files the generator did not write.
+func writeFile(name string, data []byte) error {
+	old, err := os.ReadFile(name)
+	if err == nil && !bytes.HasPrefix(old, []byte(header)) {
+		return fmt.Errorf("%s exists and was not written by msgpackgen", name)
+	}
+	return os.WriteFile(name, data, 0644)
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const msgpackPath = "github.com/elisaday/msgpack-go"
+
+// customMethods are the methods that make the reflection codec hand a type
+// over to its own encoding, so generated code must do the same.
+var customMethods = map[string]bool{
+	"EncodeMsgpack":    true,
+	"DecodeMsgpack":    true,
+	"MarshalMsgpack":   true,
+	"UnmarshalMsgpack": true,
+	"MarshalBinary":    true,
+	"UnmarshalBinary":  true,
+	"MarshalText":      true,
+	"UnmarshalText":    true,
+}
+
+type kind int
+
+const (
+	basicKind kind = iota
+	bytesKind
+	byteArrayKind
+	sliceKind
+	arrayKind
+	mapKind
+	ptrKind
+	structKind
+	timeKind
+	extKind
+	rawKind
+	fallbackKind
+)
+
+// typeInfo is a field type as far as the generator cares. name spells the
+// type in the generated code, basic is the predeclared type underneath a
+// basicKind and imports holds the packages name refers to.
+type typeInfo struct {
+	kind    kind
+	name    string
+	basic   string
+	elem    *typeInfo
+	key     *typeInfo
+	length  string
+	iface   bool
+	imports map[string]string
+}
+
+type fileInfo struct {
+	imports map[string]string // local name to path
+}
+
+type typeDecl struct {
+	spec *ast.TypeSpec
+	file *fileInfo
+}
+
+type pkgInfo struct {
+	name    string
+	fset    *token.FileSet
+	decls   map[string]*typeDecl
+	order   []string // struct types in source order
+	methods map[string]map[string]bool
+
+	resolving map[string]bool
+}
+
+// loadPackage parses the non-test files in dir, leaving out earlier output
+// of the generator.
+func loadPackage(dir string) (*pkgInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pkgInfo{
+		fset:      token.NewFileSet(),
+		decls:     map[string]*typeDecl{},
+		methods:   map[string]map[string]bool{},
+		resolving: map[string]bool{},
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		src, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(src, []byte(header)) {
+			continue
+		}
+
+		f, err := parser.ParseFile(p.fset, filepath.Join(dir, name), src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if p.name == "" {
+			p.name = f.Name.Name
+		} else if p.name != f.Name.Name {
+			return nil, fmt.Errorf("found packages %s and %s in %s", p.name, f.Name.Name, dir)
+		}
+		p.addFile(f)
+	}
+
+	if p.name == "" {
+		return nil, errors.New("no Go files in " + dir)
+	}
+	return p, nil
+}
+
+func (p *pkgInfo) addFile(f *ast.File) {
+	fi := &fileInfo{imports: map[string]string{}}
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if path == msgpackPath {
+			name = "msgpack"
+		}
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		fi.imports[name] = path
+	}
+
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range d.Specs {
+				spec := s.(*ast.TypeSpec)
+				p.decls[spec.Name.Name] = &typeDecl{spec, fi}
+				if _, ok := spec.Type.(*ast.StructType); ok && spec.Assign == 0 {
+					p.order = append(p.order, spec.Name.Name)
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				continue
+			}
+			recv := d.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if idx, ok := recv.(*ast.IndexExpr); ok {
+				recv = idx.X
+			}
+			if id, ok := recv.(*ast.Ident); ok {
+				if p.methods[id.Name] == nil {
+					p.methods[id.Name] = map[string]bool{}
+				}
+				p.methods[id.Name][d.Name.Name] = true
+			}
+		}
+	}
+}
+
+// hasCustom reports whether the named type has, or gets from an embedded
+// field, one of the customMethods.
+func (p *pkgInfo) hasCustom(name string) bool {
+	for m := range p.methods[name] {
+		if customMethods[m] {
+			return true
+		}
+	}
+
+	d := p.decls[name]
+	if d == nil {
+		return false
+	}
+	st, ok := d.spec.Type.(*ast.StructType)
+	if !ok {
+		return false
+	}
+
+	for _, f := range st.Fields.List {
+		if f.Names != nil {
+			continue
+		}
+		t := f.Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		switch t := t.(type) {
+		case *ast.Ident:
+			if t.Name != name && p.hasCustom(t.Name) {
+				return true
+			}
+		case *ast.SelectorExpr:
+			if x, ok := t.X.(*ast.Ident); ok && d.file.imports[x.Name] == "time" && t.Sel.Name == "Time" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var basicTypes = map[string]string{
+	"bool": "bool", "string": "string",
+	"int": "int", "int8": "int8", "int16": "int16", "int32": "int32", "int64": "int64",
+	"uint": "uint", "uint8": "uint8", "uint16": "uint16", "uint32": "uint32", "uint64": "uint64",
+	"uintptr": "uintptr", "float32": "float32", "float64": "float64",
+	"byte": "uint8", "rune": "int32",
+}
+
+// resolve works out how to encode a type written as e in file.
+func (p *pkgInfo) resolve(e ast.Expr, file *fileInfo) (*typeInfo, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return p.resolve(e.X, file)
+	case *ast.Ident:
+		if p.decls[e.Name] != nil {
+			return p.resolveNamed(e.Name)
+		}
+		if b, ok := basicTypes[e.Name]; ok {
+			return &typeInfo{kind: basicKind, name: e.Name, basic: b}, nil
+		}
+		if e.Name == "any" || e.Name == "error" {
+			return &typeInfo{kind: fallbackKind, name: e.Name, iface: true}, nil
+		}
+	case *ast.StarExpr:
+		elem, err := p.resolve(e.X, file)
+		if err != nil {
+			return nil, err
+		}
+		return &typeInfo{kind: ptrKind, name: "*" + elem.name, elem: elem, imports: elem.imports}, nil
+	case *ast.ArrayType:
+		elem, err := p.resolve(e.Elt, file)
+		if err != nil {
+			return nil, err
+		}
+		t := &typeInfo{elem: elem, imports: elem.imports}
+		isByte := elem.kind == basicKind && (elem.name == "byte" || elem.name == "uint8")
+		if e.Len == nil {
+			t.kind, t.name = sliceKind, "[]"+elem.name
+			if isByte {
+				t.kind = bytesKind
+			}
+		} else {
+			t.length = types.ExprString(e.Len)
+			t.kind, t.name = arrayKind, "["+t.length+"]"+elem.name
+			if isByte {
+				t.kind = byteArrayKind
+			}
+		}
+		return t, nil
+	case *ast.MapType:
+		key, err := p.resolve(e.Key, file)
+		if err != nil {
+			return nil, err
+		}
+		if key.kind != basicKind {
+			return nil, fmt.Errorf("unsupported map key type %s", key.name)
+		}
+		elem, err := p.resolve(e.Value, file)
+		if err != nil {
+			return nil, err
+		}
+		return &typeInfo{
+			kind:    mapKind,
+			name:    "map[" + key.name + "]" + elem.name,
+			key:     key,
+			elem:    elem,
+			imports: elem.imports,
+		}, nil
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok || file.imports[x.Name] == "" {
+			break
+		}
+		t := &typeInfo{
+			kind:    fallbackKind,
+			name:    x.Name + "." + e.Sel.Name,
+			imports: map[string]string{x.Name: file.imports[x.Name]},
+		}
+		switch path := file.imports[x.Name]; {
+		case path == "time" && e.Sel.Name == "Time":
+			t.kind = timeKind
+		case path == msgpackPath && e.Sel.Name == "Ext":
+			t.kind = extKind
+		case path == msgpackPath && e.Sel.Name == "RawMessage":
+			t.kind = rawKind
+		}
+		return t, nil
+	case *ast.InterfaceType:
+		return &typeInfo{kind: fallbackKind, name: types.ExprString(e), iface: true}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", types.ExprString(e))
+}
+
+// resolveNamed resolves a type declared in the package. Structs are left
+// for their own generated methods, other types take the encoding of their
+// underlying type under their own name.
+func (p *pkgInfo) resolveNamed(name string) (*typeInfo, error) {
+	d := p.decls[name]
+	if d.spec.TypeParams != nil {
+		return nil, fmt.Errorf("generic type %s is not supported", name)
+	}
+	if d.spec.Assign != 0 {
+		return p.resolve(d.spec.Type, d.file)
+	}
+	if p.hasCustom(name) {
+		return &typeInfo{kind: fallbackKind, name: name}, nil
+	}
+	if _, ok := d.spec.Type.(*ast.StructType); ok {
+		return &typeInfo{kind: structKind, name: name}, nil
+	}
+	if _, ok := d.spec.Type.(*ast.InterfaceType); ok {
+		return &typeInfo{kind: fallbackKind, name: name, iface: true}, nil
+	}
+
+	if p.resolving[name] {
+		return nil, fmt.Errorf("recursive type %s is not supported", name)
+	}
+	p.resolving[name] = true
+	defer delete(p.resolving, name)
+
+	u, err := p.resolve(d.spec.Type, d.file)
+	if err != nil {
+		return nil, err
+	}
+	t := *u
+	t.name = name
+	if t.kind == timeKind || t.kind == extKind || t.kind == rawKind {
+		t.kind, t.iface = fallbackKind, false
+	}
+	return &t, nil
+}
+
+type field struct {
+	name      string
+	path      []string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+	typ       *typeInfo
+}
+
+func structTag(lit *ast.BasicLit) (tag string) {
+	if lit == nil {
+		return ""
+	}
+	s, _ := strconv.Unquote(lit.Value)
+	if tag, ok := reflect.StructTag(s).Lookup("msgpack"); ok {
+		return tag
+	}
+	return reflect.StructTag(s).Get("json")
+}
+
+func parseTag(tag string) (name string, omitEmpty bool) {
+	name, opts, _ := strings.Cut(tag, ",")
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
+
+// structFields lists the encoded fields of the struct type name with the
+// rules the reflection codec uses: breadth first through embedded structs,
+// with the shallowest or tagged field winning a name.
+func (p *pkgInfo) structFields(name string) ([]field, error) {
+	type embedded struct {
+		name  string
+		index []int
+		path  []string
+	}
+
+	var fields []field
+	current := []embedded{}
+	next := []embedded{{name: name}}
+
+	// The fields of a struct embedded more than once at a level are
+	// recorded twice so that they cancel out.
+	count := map[string]int{}
+	nextCount := map[string]int{}
+	visited := map[string]bool{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[string]int{}
+
+		for _, e := range current {
+			if visited[e.name] {
+				continue
+			}
+			visited[e.name] = true
+
+			d := p.decls[e.name]
+			i := -1
+			for _, f := range d.spec.Type.(*ast.StructType).Fields.List {
+				names := f.Names
+				anonymous := names == nil
+				if anonymous {
+					names = []*ast.Ident{embeddedName(f.Type)}
+				}
+
+				for _, n := range names {
+					i++
+					index := append(append([]int{}, e.index...), i)
+					path := append(append([]string{}, e.path...), n.Name)
+
+					tag := structTag(f.Tag)
+					if tag == "-" {
+						continue
+					}
+					name, omitEmpty := parseTag(tag)
+
+					if anonymous {
+						promote, err := p.promoted(f.Type, d.file, name)
+						if err != nil {
+							return nil, fmt.Errorf("%s: %v", e.name, err)
+						}
+						if promote != "" {
+							nextCount[promote]++
+							if nextCount[promote] == 1 {
+								next = append(next, embedded{name: promote, index: index, path: path})
+							}
+							continue
+						}
+						if !n.IsExported() && !p.isStruct(f.Type) {
+							continue
+						}
+					} else if !n.IsExported() {
+						continue
+					}
+
+					t, err := p.resolve(f.Type, d.file)
+					if err != nil {
+						return nil, fmt.Errorf("%s.%s: %v", e.name, n.Name, err)
+					}
+					if omitEmpty && t.kind == fallbackKind && !t.iface {
+						return nil, fmt.Errorf("%s.%s: omitempty is not supported for type %s", e.name, n.Name, t.name)
+					}
+
+					tagged := name != ""
+					if name == "" {
+						name = n.Name
+					}
+					fields = append(fields, field{
+						name:      name,
+						path:      path,
+						index:     index,
+						omitEmpty: omitEmpty,
+						tagged:    tagged,
+						typ:       t,
+					})
+					if count[e.name] > 1 {
+						fields = append(fields, fields[len(fields)-1])
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		if len(fields[i].index) != len(fields[j].index) {
+			return len(fields[i].index) < len(fields[j].index)
+		}
+		return fields[i].tagged && !fields[j].tagged
+	})
+
+	out := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if len(fields[i:j]) == 1 || len(fields[i].index) != len(fields[i+1].index) ||
+			fields[i].tagged != fields[i+1].tagged {
+			out = append(out, fields[i])
+		}
+		i = j
+	}
+	fields = out
+
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields, nil
+}
+
+func (p *pkgInfo) isStruct(e ast.Expr) bool {
+	if star, ok := e.(*ast.StarExpr); ok {
+		e = star.X
+	}
+	if id, ok := e.(*ast.Ident); ok && p.decls[id.Name] != nil {
+		_, ok = p.decls[id.Name].spec.Type.(*ast.StructType)
+		return ok
+	}
+	return false
+}
+
+func embeddedName(e ast.Expr) *ast.Ident {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	case *ast.Ident:
+		return e
+	}
+	return ast.NewIdent("?")
+}
+
+// promoted returns the struct whose fields an untagged embedded field of
+// type e brings in, or "" when the field is encoded as a whole.
+func (p *pkgInfo) promoted(e ast.Expr, file *fileInfo, tagName string) (string, error) {
+	star, ptr := e.(*ast.StarExpr)
+	if ptr {
+		e = star.X
+	}
+
+	switch t := e.(type) {
+	case *ast.Ident:
+		d := p.decls[t.Name]
+		if d == nil {
+			return "", nil
+		}
+		if _, ok := d.spec.Type.(*ast.StructType); !ok || tagName != "" {
+			return "", nil
+		}
+		if ptr {
+			return "", fmt.Errorf("embedded pointer *%s is not supported", t.Name)
+		}
+		return t.Name, nil
+	case *ast.SelectorExpr:
+		x, _ := t.X.(*ast.Ident)
+		if tagName != "" || x != nil && file.imports[x.Name] == "time" && t.Sel.Name == "Time" {
+			return "", nil
+		}
+		return "", fmt.Errorf("cannot see the fields of embedded %s", types.ExprString(e))
+	}
+	return "", nil
+}
@@ -0,0 +1,98 @@
+package msgpack
+
+import "math"
+
+// Cursor unpacks values one after another from a buffer. A read that fails
+// leaves the cursor where it was, so the caller can try another type, or go
+// back to an earlier Mark with Reset to try another way of parsing.
+//
+// Positions are ints, so a Cursor can read buffers of any size. The
+// functions taking a *uint32 offset are wrappers around it that only see
+// the first 4 GiB of their buffer.
+type Cursor struct {
+	buf []byte
+	off int
+}
+
+func NewCursor(buf []byte) *Cursor {
+	return &Cursor{buf: buf}
+}
+
+// Offset returns the position of the next value in the buffer.
+func (c *Cursor) Offset() int {
+	return c.off
+}
+
+// Len returns the number of bytes not read yet.
+func (c *Cursor) Len() int {
+	if c.off >= len(c.buf) {
+		return 0
+	}
+	return len(c.buf) - c.off
+}
+
+// Mark returns a checkpoint that Reset can go back to.
+func (c *Cursor) Mark() int {
+	return c.off
+}
+
+// Reset moves the cursor back to a checkpoint returned by Mark.
+func (c *Cursor) Reset(mark int) {
+	if mark < 0 || mark > len(c.buf) {
+		panic("msgpack: reset to invalid mark")
+	}
+	c.off = mark
+}
+
+// cursorAt returns a cursor at *offset for the functions that keep the
+// position in a uint32. It cannot read past the first 4 GiB of buf, so the
+// position it ends at always fits back in *offset.
+func cursorAt(buf []byte, offset *uint32) Cursor {
+	if max := uint32(math.MaxUint32); uint64(len(buf)) > uint64(max) {
+		buf = buf[:max]
+	}
+	return Cursor{buf, int(*offset)}
+}
+
+// rollback moves the cursor back to start if *err is set. Every exported
+// method that can fail after moving the cursor defers it.
+func (c *Cursor) rollback(start int, err *error) {
+	if *err != nil {
+		c.off = start
+	}
+}
+
+func (c *Cursor) header() (header uint8, err error) {
+	if c.off >= len(c.buf) {
+		return 0, truncated(c.buf, c.off, 1)
+	}
+
+	c.off++
+	return c.buf[c.off-1], nil
+}
+
+// need moves the cursor past the next n bytes of the value starting at
+// start, if the buffer holds them. It compares n with what is left rather
+// than adding it to the position, so a huge length cannot wrap around.
+func (c *Cursor) need(start int, n uint64) error {
+	if n > uint64(c.Len()) {
+		return truncated(c.buf, start, uint64(c.off-start)+n)
+	}
+
+	c.off += int(n)
+	return nil
+}
+
+// length reads the size byte big-endian length of the value starting at
+// start.
+func (c *Cursor) length(start int, size int) (length uint32, err error) {
+	off := c.off
+	if err = c.need(start, uint64(size)); err != nil {
+		return 0, err
+	}
+
+	for _, b := range c.buf[off : off+size] {
+		length = (length << 8) | uint32(b)
+	}
+	return length, nil
+}
@@ -0,0 +1,118 @@
+package msgpack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnpackFailureKeepsOffset(t *testing.T) {
+	for _, c := range []struct {
+		b      []byte
+		unpack func(buf []byte, offset *uint32) error
+	}{
+		{[]byte{0xa1, 0x61}, func(buf []byte, offset *uint32) error { _, err := UnpackInt64(buf, offset); return err }},
+		{[]byte{0xcd, 0x1}, func(buf []byte, offset *uint32) error { _, err := UnpackUInt64(buf, offset); return err }},
+		{[]byte{0xd2, 0x1, 0x2}, func(buf []byte, offset *uint32) error { _, err := UnpackInt32(buf, offset); return err }},
+		{[]byte{0xcb, 0x1}, func(buf []byte, offset *uint32) error { _, err := UnpackDouble(buf, offset); return err }},
+		{[]byte{0xd9, 0x3, 0x61}, func(buf []byte, offset *uint32) error { _, err := UnpackString(buf, offset); return err }},
+		{[]byte{0xa1, 0xff}, func(buf []byte, offset *uint32) error { _, err := UnpackValidString(buf, offset); return err }},
+		{[]byte{0xdd, 0x0, 0x1}, func(buf []byte, offset *uint32) error { _, err := UnpackArrayHeader(buf, offset); return err }},
+		{[]byte{0xd6, 0x5, 0x0, 0x0, 0x0, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackTime(buf, offset); return err }},
+		{[]byte{0x92, 0x1, 0xa2, 0x61}, func(buf []byte, offset *uint32) error { _, err := UnpackValue(buf, offset); return err }},
+		{[]byte{0x82, 0x1, 0x2}, func(buf []byte, offset *uint32) error { return Skip(buf, offset) }},
+	} {
+		b := append([]byte{0xc0}, c.b...)
+		offset := uint32(1)
+		if err := c.unpack(b, &offset); err == nil || offset != 1 {
+			t.Errorf("wrong output for % x: %d %v", c.b, offset, err)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := NewCursor([]byte{0x1, 0xa1, 0x61, 0xc3})
+
+	if v, err := c.UnpackInt64(); err != nil || v != 1 {
+		t.Error("wrong output", v, err)
+	}
+	if _, err := c.UnpackBool(); err == nil || c.Offset() != 1 {
+		t.Error("expected mismatch without moving", c.Offset(), err)
+	}
+	if v, err := c.UnpackString(); err != nil || v != "a" {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := c.UnpackBool(); err != nil || !v {
+		t.Error("wrong output", v, err)
+	}
+	if c.Len() != 0 {
+		t.Error("wrong length", c.Len())
+	}
+	if err := c.UnpackNil(); !errors.Is(err, ErrUnpackOverflow) || c.Offset() != 4 {
+		t.Error("expected overflow", err)
+	}
+}
+
+func TestCursorMarkReset(t *testing.T) {
+	// Either a [key, value] pair or a bare value.
+	b := []byte{0x92, 0xa1, 0x6b, 0x7, 0x7}
+	c := NewCursor(b)
+
+	pair := func() (string, int64, error) {
+		n, err := c.UnpackArrayHeader()
+		if err != nil || n != 2 {
+			return "", 0, errors.New("not a pair")
+		}
+		k, err := c.UnpackString()
+		if err != nil {
+			return "", 0, err
+		}
+		v, err := c.UnpackInt64()
+		return k, v, err
+	}
+
+	for _, expected := range []string{"k", ""} {
+		mark := c.Mark()
+		k, v, err := pair()
+		if err != nil {
+			c.Reset(mark)
+			v, err = c.UnpackInt64()
+		}
+		if err != nil || k != expected || v != 7 {
+			t.Error("wrong output", k, v, err)
+		}
+	}
+
+	if c.Len() != 0 {
+		t.Error("wrong length", c.Len())
+	}
+}
+
+func TestCursorHugeLength(t *testing.T) {
+	for _, c := range []struct {
+		b      []byte
+		needed int64
+	}{
+		{[]byte{0xc9, 0xff, 0xff, 0xff, 0xff, 0x1}, 6 + 0xffffffff},
+		{[]byte{0xdb, 0xff, 0xff, 0xff, 0xff, 0x61}, 5 + 0xffffffff},
+		{[]byte{0xc6, 0xff, 0xff, 0xff, 0xfe, 0x1, 0x2}, 5 + 0xfffffffe},
+	} {
+		cur := NewCursor(append([]byte{0xc0}, c.b...))
+		cur.Reset(1)
+
+		var e *TruncatedError
+		if err := cur.Skip(); !errors.As(err, &e) || cur.Offset() != 1 {
+			t.Errorf("wrong output for % x: %d %v", c.b, cur.Offset(), err)
+		} else if e.Offset != 1 || int64(e.Needed) != c.needed || e.Have != len(c.b) {
+			t.Errorf("wrong error for % x: %+v", c.b, e)
+		}
+	}
+}
+
+func TestCursorResetInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewCursor([]byte{0xc0}).Reset(2)
+}
@@ -0,0 +1,164 @@
+package msgpack
+
+import (
+	"encoding"
+	"reflect"
+)
+
+// Marshaler is implemented by types that encode themselves. MarshalMsgpack
+// must return exactly one MessagePack value.
+type Marshaler interface {
+	MarshalMsgpack() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that decode themselves. The data is
+// the whole encoded value and must be copied if it is kept after
+// UnmarshalMsgpack returns.
+type Unmarshaler interface {
+	UnmarshalMsgpack(data []byte) error
+}
+
+// CustomEncoder is Marshaler for types that write themselves straight to
+// the Encoder instead of returning a buffer.
+type CustomEncoder interface {
+	EncodeMsgpack(e *Encoder) error
+}
+
+// CustomDecoder is Unmarshaler for types that read themselves straight
+// from a Cursor. The cursor ends at the end of the value, and whatever
+// DecodeMsgpack leaves unread of it is skipped.
+type CustomDecoder interface {
+	DecodeMsgpack(c *Cursor) error
+}
+
+// MarshalerError wraps an error returned by a MarshalMsgpack, EncodeMsgpack,
+// MarshalBinary or MarshalText method, or the error found in the output of
+// MarshalMsgpack.
+type MarshalerError struct {
+	Type   reflect.Type
+	Method string
+	Err    error
+}
+
+func (e *MarshalerError) Error() string {
+	return "error calling " + e.Method + " for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+func (e *MarshalerError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	marshalerType         = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType       = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	customEncoderType     = reflect.TypeOf((*CustomEncoder)(nil)).Elem()
+	customDecoderType     = reflect.TypeOf((*CustomDecoder)(nil)).Elem()
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// implementer returns v, or its address when only the pointer has the
+// method, as a value implementing t.
+func implementer(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	if v.Kind() != reflect.Interface && v.Type().Implements(t) {
+		return v, true
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(t) {
+		return v.Addr(), true
+	}
+	return v, false
+}
+
+// encodeCustom encodes v with the first of its EncodeMsgpack,
+// MarshalMsgpack, MarshalBinary and MarshalText methods. It reports false
+// if v has none of them.
+func (e *Encoder) encodeCustom(v reflect.Value) (bool, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return false, nil
+	}
+
+	if m, ok := implementer(v, customEncoderType); ok {
+		if err := m.Interface().(CustomEncoder).EncodeMsgpack(e); err != nil {
+			return true, &MarshalerError{v.Type(), "EncodeMsgpack", err}
+		}
+		return true, e.err
+	}
+
+	if m, ok := implementer(v, marshalerType); ok {
+		b, err := m.Interface().(Marshaler).MarshalMsgpack()
+		if err == nil {
+			var off uint32
+			if err = Skip(b, &off); err == nil && int(off) != len(b) {
+				err = ErrTrailingData
+			}
+		}
+		if err != nil {
+			return true, &MarshalerError{v.Type(), "MarshalMsgpack", err}
+		}
+		return true, e.writeRaw(b)
+	}
+
+	if m, ok := implementer(v, binaryMarshalerType); ok {
+		b, err := m.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return true, &MarshalerError{v.Type(), "MarshalBinary", err}
+		}
+		return true, e.EncodeBinary(b)
+	}
+
+	if m, ok := implementer(v, textMarshalerType); ok {
+		b, err := m.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return true, &MarshalerError{v.Type(), "MarshalText", err}
+		}
+		return true, e.EncodeString(string(b))
+	}
+
+	return false, nil
+}
+
+// decodeCustom is encodeCustom for decoding, calling DecodeMsgpack,
+// UnmarshalMsgpack, UnmarshalBinary or UnmarshalText. When v has both of
+// the last two, str and raw data go to UnmarshalText.
+func (d *decodeState) decodeCustom(v reflect.Value) (bool, error) {
+	if m, ok := implementer(v, customDecoderType); ok {
+		start := d.off
+		if err := d.Skip(); err != nil {
+			return true, err
+		}
+		end := d.off
+
+		c := &Cursor{d.buf[:end], start}
+		return true, m.Interface().(CustomDecoder).DecodeMsgpack(c)
+	}
+
+	if m, ok := implementer(v, unmarshalerType); ok {
+		start := d.off
+		if err := d.Skip(); err != nil {
+			return true, err
+		}
+		return true, m.Interface().(Unmarshaler).UnmarshalMsgpack(d.buf[start:d.off])
+	}
+
+	bm, isBinary := implementer(v, binaryUnmarshalerType)
+	tm, isText := implementer(v, textUnmarshalerType)
+	if !isBinary && !isText {
+		return false, nil
+	}
+
+	if isText && (!isBinary || d.Len() > 0 && typeOfHeader(d.buf[d.off]) == RawType) {
+		b, err := d.UnpackRawBuffer()
+		if err != nil {
+			return true, err
+		}
+		return true, tm.Interface().(encoding.TextUnmarshaler).UnmarshalText(b)
+	}
+
+	b, err := d.UnpackRawBuffer()
+	if err != nil {
+		return true, err
+	}
+	return true, bm.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(b)
+}
@@ -0,0 +1,154 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// money is written as [units, cents].
+type money struct {
+	Units int64
+	Cents int64
+}
+
+func (m money) MarshalMsgpack() ([]byte, error) {
+	b := AppendArrayHeader(nil, 2)
+	b = AppendInt64(b, m.Units)
+	return AppendInt64(b, m.Cents), nil
+}
+
+func (m *money) UnmarshalMsgpack(data []byte) error {
+	c := NewCursor(data)
+	if n, err := c.UnpackArrayHeader(); err != nil || n != 2 {
+		return errors.New("money is not a pair")
+	}
+	var err error
+	if m.Units, err = c.UnpackInt64(); err != nil {
+		return err
+	}
+	m.Cents, err = c.UnpackInt64()
+	return err
+}
+
+// flags is written as a string of the letters of the bits set.
+type flags uint8
+
+func (f flags) EncodeMsgpack(e *Encoder) error {
+	var s []byte
+	for i := uint(0); i < 8; i++ {
+		if f&(1<<i) != 0 {
+			s = append(s, 'a'+byte(i))
+		}
+	}
+	return e.EncodeString(string(s))
+}
+
+func (f *flags) DecodeMsgpack(c *Cursor) error {
+	s, err := c.UnpackString()
+	if err != nil {
+		return err
+	}
+	*f = 0
+	for _, r := range s {
+		*f |= 1 << uint(r-'a')
+	}
+	return nil
+}
+
+// upper is a TextMarshaler only.
+type upper string
+
+func (u upper) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(string(u))), nil
+}
+
+func (u *upper) UnmarshalText(b []byte) error {
+	*u = upper(strings.ToLower(string(b)))
+	return nil
+}
+
+type customStruct struct {
+	Price money
+	Tax   *money
+	Flags flags
+	Name  upper
+	IP    net.IP
+}
+
+func TestMarshalCustom(t *testing.T) {
+	in := customStruct{money{3, 50}, &money{0, 7}, 5, "abc", net.IPv4(1, 2, 3, 4)}
+
+	b, err := Marshal(&in)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	expected := []byte{0x85,
+		0xa5, 'P', 'r', 'i', 'c', 'e', 0x92, 0x3, 0x32,
+		0xa3, 'T', 'a', 'x', 0x92, 0x0, 0x7,
+		0xa5, 'F', 'l', 'a', 'g', 's', 0xa2, 'a', 'c',
+		0xa4, 'N', 'a', 'm', 'e', 0xa3, 'A', 'B', 'C',
+		0xa2, 'I', 'P', 0xa7, '1', '.', '2', '.', '3', '.', '4'}
+	if bytes.Compare(b, expected) != 0 {
+		t.Errorf("wrong output % x", b)
+	}
+
+	var out customStruct
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal("err != nil", err)
+	}
+	in.Name = "abc"
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("wrong output %+v", out)
+	}
+}
+
+type badMarshaler struct{}
+
+func (badMarshaler) MarshalMsgpack() ([]byte, error) {
+	return []byte{0x1, 0x2}, nil
+}
+
+type failingEncoder struct{}
+
+func (failingEncoder) EncodeMsgpack(e *Encoder) error {
+	return errors.New("boom")
+}
+
+func TestMarshalCustomErrors(t *testing.T) {
+	var e *MarshalerError
+	if _, err := Marshal(badMarshaler{}); !errors.As(err, &e) || e.Method != "MarshalMsgpack" || e.Err != ErrTrailingData {
+		t.Error("expected MarshalerError", err)
+	}
+
+	if _, err := Marshal(failingEncoder{}); !errors.As(err, &e) || e.Method != "EncodeMsgpack" {
+		t.Error("expected MarshalerError", err)
+	}
+
+	var m *money
+	if b, err := Marshal(m); err != nil || bytes.Compare(b, []byte{0xc0}) != 0 {
+		t.Error("wrong output", b, err)
+	}
+}
+
+func TestDecoderCustom(t *testing.T) {
+	b := &bytes.Buffer{}
+	enc := NewEncoder(b)
+	enc.Encode(flags(3))
+	enc.Encode(money{1, 2})
+	enc.Flush()
+
+	d := NewDecoder(b)
+	var f flags
+	var m money
+	if err := d.Decode(&f); err != nil || f != 3 {
+		t.Error("wrong output", f, err)
+	}
+	if err := d.Decode(&m); err != nil || m != (money{1, 2}) {
+		t.Error("wrong output", m, err)
+	}
+}
@@ -0,0 +1,405 @@
+package msgpack
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal decodes the MessagePack value in data into the value pointed to
+// by v, reversing the mapping used by Marshal.
+//
+// Numbers of any encoding are accepted for any integer or float kind as
+// long as the value fits, following the Unpack* functions, and raw, str and
+// bin data are all accepted for strings and []byte. Decoding into an empty
+// interface yields nil, bool, int64, uint64, float32, float64, string,
+// []byte, time.Time, Ext, []interface{} or map[string]interface{}, as
+// UnpackValue does. A RawMessage receives a copy of the encoded value, and
+// types in the ext registry are decoded from their ext code. Types
+// implementing CustomDecoder, Unmarshaler, encoding.BinaryUnmarshaler or
+// encoding.TextUnmarshaler decode themselves, the way Marshal encodes them.
+// Struct fields are matched by the names Marshal uses, preferring an exact
+// match over a case-insensitive one, and unknown keys are ignored. Arrays
+// and maps nested more than 10000 levels deep fail with a DepthError.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	d := &decodeState{Cursor: Cursor{buf: data}}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+
+	if d.off != len(data) {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+var ErrTrailingData = errors.New("trailing data after value")
+
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "unmarshal(nil)"
+	}
+
+	if e.Type.Kind() != reflect.Ptr {
+		return "unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "unmarshal(nil " + e.Type.String() + ")"
+}
+
+type decodeState struct {
+	Cursor
+	ext   *ExtRegistry
+	depth int // arrays and maps being decoded
+}
+
+// enter counts the array or map at the cursor as being decoded, failing
+// past MaxDepth. leave must be called when it is done.
+func (d *decodeState) enter() error {
+	if d.depth == MaxDepth {
+		return &DepthError{d.off}
+	}
+	d.depth++
+	return nil
+}
+
+func (d *decodeState) leave() {
+	d.depth--
+}
+
+func (d *decodeState) decode(v reflect.Value) error {
+	if d.IsNil() && v.Type() != rawMessageType {
+		d.off++
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		t, err := d.UnpackTime()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case rawMessageType:
+		start := d.off
+		if err := d.Skip(); err != nil {
+			return err
+		}
+		v.SetBytes(append(make([]byte, 0, d.off-start), d.buf[start:d.off]...))
+		return nil
+	case extType:
+		code, data, err := d.UnpackExt()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(Ext{code, append(make([]byte, 0, len(data)), data...)}))
+		return nil
+	}
+
+	if x := d.ext.lookupType(v.Type()); x != nil {
+		start := d.off
+		code, payload, err := d.UnpackExt()
+		if err == nil && code != x.code {
+			d.off = start
+			err = mismatch(d.buf, start, "ext "+strconv.Itoa(int(x.code)))
+		}
+		if err != nil {
+			return err
+		}
+		val, err := x.decodeExt(payload)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(val))
+		return nil
+	}
+
+	if v.Kind() != reflect.Ptr {
+		if ok, err := d.decodeCustom(v); ok {
+			return err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			if v.IsNil() || v.Elem().Kind() != reflect.Ptr {
+				return &UnsupportedTypeError{v.Type()}
+			}
+			return d.decode(v.Elem())
+		}
+		val, err := d.valueInterface()
+		if err != nil {
+			return err
+		}
+		if val == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(val))
+		}
+		return nil
+	case reflect.Bool:
+		b, err := d.UnpackBool()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return d.decodeInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return d.decodeUint(v)
+	case reflect.Float32, reflect.Float64:
+		return d.decodeFloat(v)
+	case reflect.String:
+		b, err := d.UnpackRawBuffer()
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := d.UnpackRawBuffer()
+			if err != nil {
+				return err
+			}
+			v.SetBytes(append(make([]byte, 0, len(b)), b...))
+			return nil
+		}
+		return d.decodeSlice(v)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := d.UnpackRawBuffer()
+			if err != nil {
+				return err
+			}
+			n := reflect.Copy(v, reflect.ValueOf(b))
+			for ; n < v.Len(); n++ {
+				v.Index(n).Set(reflect.Zero(v.Type().Elem()))
+			}
+			return nil
+		}
+		return d.decodeArray(v)
+	case reflect.Map:
+		return d.decodeMap(v)
+	case reflect.Struct:
+		return d.decodeStruct(v)
+	}
+	return &UnsupportedTypeError{v.Type()}
+}
+
+func (d *decodeState) decodeInt(v reflect.Value) error {
+	bits := uint(v.Type().Bits())
+	n, err := d.unpackInt(-1<<(bits-1), 1<<(bits-1)-1, v.Type().String())
+	if err != nil {
+		return err
+	}
+	v.SetInt(n)
+	return nil
+}
+
+func (d *decodeState) decodeUint(v reflect.Value) error {
+	bits := uint(v.Type().Bits())
+	u, err := d.unpackUint(math.MaxUint64>>(64-bits), v.Type().String())
+	if err != nil {
+		return err
+	}
+	v.SetUint(u)
+	return nil
+}
+
+func (d *decodeState) decodeFloat(v reflect.Value) error {
+	max := float64(math.MaxFloat64)
+	if v.Kind() == reflect.Float32 {
+		max = math.MaxFloat32
+	}
+
+	f, err := d.unpackFloat(max, v.Type().String())
+	if err != nil {
+		return err
+	}
+	v.SetFloat(f)
+	return nil
+}
+
+func (d *decodeState) decodeSlice(v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	n, err := d.UnpackArrayHeader()
+	if err != nil {
+		return err
+	}
+	if err = d.checkContainerLength(n); err != nil {
+		return err
+	}
+
+	s := reflect.MakeSlice(v.Type(), int(n), int(n))
+	for i := 0; i < int(n); i++ {
+		if err = d.decode(s.Index(i)); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+func (d *decodeState) decodeArray(v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	n, err := d.UnpackArrayHeader()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(n); i++ {
+		if i < v.Len() {
+			err = d.decode(v.Index(i))
+		} else {
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := int(n); i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+	return nil
+}
+
+func (d *decodeState) decodeMap(v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	n, err := d.UnpackMapHeader()
+	if err != nil {
+		return err
+	}
+	if err = d.checkContainerLength(n); err != nil {
+		return err
+	}
+
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, int(n)))
+	}
+
+	for i := 0; i < int(n); i++ {
+		key := reflect.New(t.Key()).Elem()
+		if err = d.decode(key); err != nil {
+			return err
+		}
+		if key.Kind() == reflect.Interface && !key.IsNil() && !key.Elem().Type().Comparable() {
+			return ErrUnhashableKey
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err = d.decode(elem); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+func (d *decodeState) decodeStruct(v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	n, err := d.UnpackMapHeader()
+	if err != nil {
+		return err
+	}
+
+	fields := cachedFields(v.Type())
+
+	for i := 0; i < int(n); i++ {
+		key, err := d.UnpackRawBuffer()
+		if err != nil {
+			return err
+		}
+
+		f := findField(fields, string(key))
+		if f == nil {
+			err = d.Skip()
+		} else {
+			var fv reflect.Value
+			if fv, err = fieldByIndexAlloc(v, f.index); err == nil {
+				err = d.decode(fv)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndexAlloc is reflect.Value.FieldByIndex that allocates nil
+// embedded pointers on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, errors.New("cannot set embedded pointer to unexported struct " +
+						v.Type().Elem().String())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+func findField(fields []field, name string) *field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func (d *decodeState) valueInterface() (val interface{}, err error) {
+	return d.unpackValue(&ValueOptions{ExtRegistry: d.ext}, d.depth)
+}
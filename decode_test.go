@@ -0,0 +1,220 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type unmarshalStruct struct {
+	Name  string
+	Count uint16
+	Ratio float64
+	Data  []byte
+	Tags  []string
+	Attrs map[string]int
+	Inner *marshalInner
+	When  time.Time
+	Any   interface{}
+	Fixed [2]int
+}
+
+func TestUnmarshalRoundTrip(t *testing.T) {
+	in := unmarshalStruct{
+		Name:  "hello",
+		Count: 65535,
+		Ratio: 0.5,
+		Data:  []byte{0x1, 0x2},
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]int{"x": -1, "y": 1000},
+		Inner: &marshalInner{X: 7},
+		When:  time.Unix(1500000000, 123).UTC(),
+		Any:   []interface{}{int64(1), "s", map[string]interface{}{"k": true}},
+		Fixed: [2]int{3, 4},
+	}
+
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	var out unmarshalStruct
+	if err = Unmarshal(b, &out); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("wrong output %#v", out)
+	}
+}
+
+func TestUnmarshalFieldNames(t *testing.T) {
+	// {"name": "a", "Unknown": [1, {"x": 2}], "COUNT": 3}
+	b := []byte{0x83, 0xa4, 0x6e, 0x61, 0x6d, 0x65, 0xa1, 0x61,
+		0xa7, 0x55, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x92, 0x1, 0x81, 0xa1, 0x78, 0x2,
+		0xa5, 0x43, 0x4f, 0x55, 0x4e, 0x54, 0x3}
+
+	var out unmarshalStruct
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if out.Name != "a" || out.Count != 3 {
+		t.Errorf("wrong output %#v", out)
+	}
+}
+
+func TestUnmarshalIntegers(t *testing.T) {
+	var i8 int8
+	if err := Unmarshal([]byte{0xcc, 0x7f}, &i8); err != nil || i8 != 127 {
+		t.Error("wrong output", i8, err)
+	}
+
+	if err := Unmarshal([]byte{0xcc, 0x80}, &i8); err == nil {
+		t.Error("expected overflow")
+	}
+
+	var u uint
+	if err := Unmarshal([]byte{0xd1, 0x1, 0x0}, &u); err != nil || u != 256 {
+		t.Error("wrong output", u, err)
+	}
+
+	if err := Unmarshal([]byte{0xff}, &u); err == nil {
+		t.Error("expected overflow for negative value")
+	}
+
+	var i64 int64
+	if err := Unmarshal([]byte{0xcf, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}, &i64); err == nil {
+		t.Error("expected overflow")
+	}
+
+	var e *OverflowError
+	if err := Unmarshal([]byte{0xcd, 0x1, 0x0}, &i8); !errors.As(err, &e) || e.Target != "int8" || e.Value != uint64(256) {
+		t.Error("expected OverflowError", err)
+	}
+
+	if err := Unmarshal([]byte{0xca, 0x40, 0x40, 0x0, 0x0}, &i8); err != nil || i8 != 3 {
+		t.Error("wrong output", i8, err)
+	}
+
+	var f32 float32
+	if err := Unmarshal([]byte{0xd0, 0x80}, &f32); err != nil || f32 != -128 {
+		t.Error("wrong output", f32, err)
+	}
+}
+
+func TestUnmarshalNil(t *testing.T) {
+	p := &marshalInner{X: 1}
+	m := map[string]int{"a": 1}
+	s := []int{1}
+	i := 5
+
+	for _, v := range []interface{}{&p, &m, &s, &i} {
+		if err := Unmarshal([]byte{0xc0}, v); err != nil {
+			t.Error("err != nil", err)
+		}
+	}
+
+	if p != nil || m != nil || s != nil || i != 5 {
+		t.Error("wrong output", p, m, s, i)
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	b := []byte{0x96, 0xc0, 0xc3, 0xcc, 0xc8, 0xd0, 0x80, 0xc4, 0x1, 0x1,
+		0x81, 0xa1, 0x61, 0xca, 0x3f, 0x80, 0x0, 0x0}
+
+	var out interface{}
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	expected := []interface{}{nil, true, uint64(200), int64(-128), []byte{0x1},
+		map[string]interface{}{"a": float32(1)}}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("wrong output %#v", out)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var i int
+	if _, ok := Unmarshal([]byte{0x1}, i).(*InvalidUnmarshalError); !ok {
+		t.Error("expected InvalidUnmarshalError")
+	}
+
+	if err := Unmarshal([]byte{0x1, 0x2}, &i); err != ErrTrailingData {
+		t.Error("expected ErrTrailingData", err)
+	}
+
+	var s []int
+	if err := Unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, &s); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("expected overflow", err)
+	}
+
+	var str string
+	if err := Unmarshal([]byte{0x1}, &str); err == nil {
+		t.Error("expected error for int into string")
+	}
+
+	var b [2]byte
+	if err := Unmarshal([]byte{0xa3, 0x61, 0x62, 0x63}, &b); err != nil || bytes.Compare(b[:], []byte("ab")) != 0 {
+		t.Error("wrong output", b, err)
+	}
+}
+
+func TestUnmarshalUnhashableKey(t *testing.T) {
+	var m map[interface{}]interface{}
+	if err := Unmarshal([]byte{0x81, 0x91, 0x1, 0x2}, &m); err != ErrUnhashableKey {
+		t.Error("expected ErrUnhashableKey", err)
+	}
+	if err := Unmarshal([]byte{0x81, 0xd4, 0x5, 0x1, 0x2}, &m); err != ErrUnhashableKey {
+		t.Error("expected ErrUnhashableKey", err)
+	}
+
+	if err := Unmarshal([]byte{0x82, 0xa1, 'a', 0x1, 0xc0, 0x2}, &m); err != nil || m["a"] != int64(1) || m[nil] != int64(2) {
+		t.Error("wrong output", m, err)
+	}
+
+	d := NewDecoder(bytes.NewReader([]byte{0x81, 0x80, 0x1}))
+	if err := d.Decode(&m); err != ErrUnhashableKey {
+		t.Error("expected ErrUnhashableKey", err)
+	}
+}
+
+type deepList []deepList
+
+type deepNode struct {
+	Next *deepNode
+}
+
+func TestUnmarshalDepth(t *testing.T) {
+	ok := append(bytes.Repeat([]byte{0x91}, MaxDepth), 0xc0)
+	var l deepList
+	if err := Unmarshal(ok, &l); err != nil {
+		t.Error("err != nil", err)
+	}
+
+	deep := append(bytes.Repeat([]byte{0x91}, 20000000), 0xc0)
+	var e *DepthError
+	var iface interface{}
+	if err := Unmarshal(deep, &iface); !errors.As(err, &e) || e.Offset != MaxDepth {
+		t.Error("expected DepthError", err)
+	}
+	if err := Unmarshal(deep, &l); !errors.As(err, &e) || e.Offset != MaxDepth {
+		t.Error("expected DepthError", err)
+	}
+
+	// Arrays into an interface{} field count towards the same limit.
+	var arr [1]interface{}
+	if err := Unmarshal(deep, &arr); !errors.As(err, &e) || e.Offset != MaxDepth {
+		t.Error("expected DepthError", err)
+	}
+
+	nodes := append(bytes.Repeat([]byte{0x81, 0xa4, 'N', 'e', 'x', 't'}, MaxDepth+1), 0xc0)
+	var n deepNode
+	if err := Unmarshal(nodes, &n); !errors.As(err, &e) || e.Offset != 6*MaxDepth {
+		t.Error("expected DepthError", err)
+	}
+}
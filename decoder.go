@@ -0,0 +1,363 @@
+package msgpack
+
+import (
+	"io"
+	"reflect"
+	"time"
+)
+
+// A Decoder reads MessagePack values from an input stream. It buffers the
+// input and reads more whenever a value does not fit in what it has, so
+// values may cross the boundaries of the underlying reads.
+type Decoder struct {
+	r   io.Reader
+	buf []byte
+	off int
+	err error
+	ext *ExtRegistry
+}
+
+const minDecoderRead = 512
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetExtRegistry makes Decode and DecodeValue use r for ext types instead
+// of the registry of RegisterExt.
+func (d *Decoder) SetExtRegistry(r *ExtRegistry) {
+	d.ext = r
+}
+
+// fill discards the consumed part of the buffer and reads more input,
+// waiting for up to missing bytes. The buffer at most doubles each time:
+// missing comes from lengths in the input, which may claim far more data
+// than will ever arrive.
+func (d *Decoder) fill(missing int) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if d.off > 0 {
+		n := copy(d.buf, d.buf[d.off:])
+		d.buf = d.buf[:n]
+		d.off = 0
+	}
+
+	min := missing
+	if limit := len(d.buf) + minDecoderRead; min > limit {
+		min = limit
+	}
+	if min < 1 {
+		min = 1
+	}
+
+	if free := cap(d.buf) - len(d.buf); free < minDecoderRead || free < min {
+		buf := make([]byte, len(d.buf), 2*cap(d.buf)+minDecoderRead)
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+
+	n, err := io.ReadAtLeast(d.r, d.buf[len(d.buf):cap(d.buf)], min)
+	d.buf = d.buf[:len(d.buf)+n]
+	if err != nil {
+		d.err = err
+		if n > 0 {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// unpack runs f on a cursor over the buffered input, reading more and
+// running it again for as long as f reports a TruncatedError. The offset
+// only moves when f succeeds.
+func (d *Decoder) unpack(f func(c *Cursor) error) error {
+	for {
+		c := Cursor{d.buf, d.off}
+		err := f(&c)
+		e, ok := err.(*TruncatedError)
+		if !ok {
+			if err == nil {
+				d.off = c.off
+			}
+			return err
+		}
+
+		if err = d.more(e); err != nil {
+			return err
+		}
+	}
+}
+
+// unpackValue runs f once on a cursor over the whole next value. Values
+// with nested ones are buffered this way, since running f again after
+// every read would go over the same data again and again.
+func (d *Decoder) unpackValue(f func(c *Cursor) error) error {
+	end, err := d.buffer()
+	if err != nil {
+		return err
+	}
+
+	c := Cursor{d.buf[:end], d.off}
+	if err = f(&c); err == nil {
+		d.off = end
+	}
+	return err
+}
+
+// buffer reads until the next value is buffered whole and returns where it
+// ends. It keeps its place in the value between reads, so each byte is
+// scanned once.
+func (d *Decoder) buffer() (end int, err error) {
+	scanned, remaining := 0, uint64(1)
+	for {
+		c := Cursor{d.buf, d.off + scanned}
+		remaining, err = c.skipValues(remaining)
+		e, ok := err.(*TruncatedError)
+		if !ok {
+			return c.off, err
+		}
+
+		scanned = c.off - d.off
+		if err = d.more(e); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// more reads the input a TruncatedError asks for.
+func (d *Decoder) more(e *TruncatedError) error {
+	err := d.fill(e.Offset + e.Needed - len(d.buf))
+	if err == io.EOF && d.off < len(d.buf) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Decode reads the next value and stores it in the value pointed to by v,
+// following the rules of Unmarshal.
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	// The whole value is buffered before anything is stored in v.
+	return d.unpackValue(func(c *Cursor) error {
+		ds := &decodeState{Cursor: *c, ext: d.ext}
+		return ds.decode(rv.Elem())
+	})
+}
+
+// Skip reads past the next value without decoding it.
+func (d *Decoder) Skip() error {
+	return d.unpackValue(func(c *Cursor) error { return nil })
+}
+
+func (d *Decoder) IsNil() (val bool, err error) {
+	err = d.unpack(func(c *Cursor) error {
+		if c.Len() == 0 {
+			return truncated(c.buf, c.off, 1)
+		}
+		val = c.IsNil()
+		return nil
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeNil() error {
+	return d.unpack((*Cursor).UnpackNil)
+}
+
+func (d *Decoder) DecodeUInt64() (val uint64, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackUInt64()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeUInt32() (val uint32, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackUInt32()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeInt64() (val int64, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackInt64()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeInt32() (val int32, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackInt32()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeUInt16() (val uint16, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackUInt16()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeInt16() (val int16, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackInt16()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeUInt8() (val uint8, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackUInt8()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeInt8() (val int8, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackInt8()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeUInt() (val uint, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackUInt()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeInt() (val int, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackInt()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeBool() (val bool, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackBool()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeFloat() (val float32, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackFloat()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeDouble() (val float64, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackDouble()
+		return err
+	})
+	return val, err
+}
+
+// DecodeRawBuffer returns a copy of the data, since the Decoder reuses its
+// buffer. The same goes for DecodeBinary and DecodeExt.
+func (d *Decoder) DecodeRawBuffer() (val []byte, err error) {
+	err = d.unpack(func(c *Cursor) error {
+		b, err := c.UnpackRawBuffer()
+		val = append(make([]byte, 0, len(b)), b...)
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeString() (val string, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackString()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeBinary() (val []byte, err error) {
+	err = d.unpack(func(c *Cursor) error {
+		b, err := c.UnpackBinary()
+		val = append(make([]byte, 0, len(b)), b...)
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeArrayHeader() (length uint32, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		length, err = c.UnpackArrayHeader()
+		return err
+	})
+	return length, err
+}
+
+func (d *Decoder) DecodeMapHeader() (length uint32, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		length, err = c.UnpackMapHeader()
+		return err
+	})
+	return length, err
+}
+
+func (d *Decoder) DecodeExt() (typeCode int8, payload []byte, err error) {
+	err = d.unpack(func(c *Cursor) error {
+		code, b, err := c.UnpackExt()
+		typeCode, payload = code, append(make([]byte, 0, len(b)), b...)
+		return err
+	})
+	return typeCode, payload, err
+}
+
+func (d *Decoder) DecodeTime() (val time.Time, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		val, err = c.UnpackTime()
+		return err
+	})
+	return val, err
+}
+
+func (d *Decoder) DecodeValue() (val interface{}, err error) {
+	return d.DecodeValueWithOptions(ValueOptions{})
+}
+
+// DecodeValueWithOptions uses the Decoder's ext registry when opts has
+// none.
+func (d *Decoder) DecodeValueWithOptions(opts ValueOptions) (val interface{}, err error) {
+	if opts.ExtRegistry == nil {
+		opts.ExtRegistry = d.ext
+	}
+	err = d.unpackValue(func(c *Cursor) (err error) {
+		val, err = c.UnpackValueWithOptions(opts)
+		return err
+	})
+	return val, err
+}
+
+// PeekType returns the type of the next value without consuming it.
+func (d *Decoder) PeekType() (info TypeInfo, err error) {
+	err = d.unpack(func(c *Cursor) (err error) {
+		info, err = c.NextType()
+		return err
+	})
+	return info, err
+}
@@ -0,0 +1,199 @@
+package msgpack
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestDecoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	PackInt64(b, -129)
+	PackUInt64(b, 18446744073709551615)
+	PackInt32(b, 2147483647)
+	PackUInt32(b, 65536)
+	PackNil(b)
+	PackBool(b, true)
+	PackFloat(b, 3.1415926)
+	PackDouble(b, 3.1416786785926)
+	PackRawBuffer(b, bytes.Repeat([]byte{'r'}, 1000))
+	PackString(b, "hello world")
+	PackBinary(b, []byte{0x1, 0x2})
+	PackArrayHeader(b, 20)
+	PackMapHeader(b, 70000)
+	PackExt(b, 5, []byte{0x1, 0x2, 0x3})
+	PackTime(b, time.Unix(1, 2))
+
+	d := NewDecoder(iotest.OneByteReader(b))
+
+	if v, err := d.DecodeInt64(); err != nil || v != -129 {
+		t.Error("wrong output for DecodeInt64", v, err)
+	}
+	if v, err := d.DecodeUInt64(); err != nil || v != 18446744073709551615 {
+		t.Error("wrong output for DecodeUInt64", v, err)
+	}
+	if v, err := d.DecodeInt32(); err != nil || v != 2147483647 {
+		t.Error("wrong output for DecodeInt32", v, err)
+	}
+	if v, err := d.DecodeUInt32(); err != nil || v != 65536 {
+		t.Error("wrong output for DecodeUInt32", v, err)
+	}
+	if v, err := d.IsNil(); err != nil || !v {
+		t.Error("wrong output for IsNil", v, err)
+	}
+	if err := d.DecodeNil(); err != nil {
+		t.Error("wrong output for DecodeNil", err)
+	}
+	if v, err := d.DecodeBool(); err != nil || !v {
+		t.Error("wrong output for DecodeBool", v, err)
+	}
+	if v, err := d.DecodeFloat(); err != nil || v != 3.1415926 {
+		t.Error("wrong output for DecodeFloat", v, err)
+	}
+	if v, err := d.DecodeDouble(); err != nil || v != 3.1416786785926 {
+		t.Error("wrong output for DecodeDouble", v, err)
+	}
+	if v, err := d.DecodeRawBuffer(); err != nil || bytes.Compare(v, bytes.Repeat([]byte{'r'}, 1000)) != 0 {
+		t.Error("wrong output for DecodeRawBuffer", err)
+	}
+	if v, err := d.DecodeString(); err != nil || v != "hello world" {
+		t.Error("wrong output for DecodeString", v, err)
+	}
+	if v, err := d.DecodeBinary(); err != nil || bytes.Compare(v, []byte{0x1, 0x2}) != 0 {
+		t.Error("wrong output for DecodeBinary", v, err)
+	}
+	if v, err := d.DecodeArrayHeader(); err != nil || v != 20 {
+		t.Error("wrong output for DecodeArrayHeader", v, err)
+	}
+	if v, err := d.DecodeMapHeader(); err != nil || v != 70000 {
+		t.Error("wrong output for DecodeMapHeader", v, err)
+	}
+	if c, v, err := d.DecodeExt(); err != nil || c != 5 || bytes.Compare(v, []byte{0x1, 0x2, 0x3}) != 0 {
+		t.Error("wrong output for DecodeExt", c, v, err)
+	}
+	if v, err := d.DecodeTime(); err != nil || !v.Equal(time.Unix(1, 2)) {
+		t.Error("wrong output for DecodeTime", v, err)
+	}
+
+	if _, err := d.DecodeInt64(); err != io.EOF {
+		t.Error("expected io.EOF", err)
+	}
+}
+
+func TestDecoderDecode(t *testing.T) {
+	b := &bytes.Buffer{}
+	in := []unmarshalStruct{
+		{Name: "a", Tags: []string{"x", "y"}, Data: bytes.Repeat([]byte{0x1}, 3000)},
+		{Name: "b", Attrs: map[string]int{"k": 1}},
+	}
+
+	for _, v := range in {
+		p, err := Marshal(v)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+		b.Write(p)
+	}
+
+	d := NewDecoder(iotest.HalfReader(b))
+
+	for i := range in {
+		var out unmarshalStruct
+		if err := d.Decode(&out); err != nil || !reflect.DeepEqual(out, in[i]) {
+			t.Errorf("wrong output %#v %v", out, err)
+		}
+	}
+
+	var out unmarshalStruct
+	if err := d.Decode(&out); err != io.EOF {
+		t.Error("expected io.EOF", err)
+	}
+}
+
+func TestDecoderUnexpectedEOF(t *testing.T) {
+	d := NewDecoder(bytes.NewReader([]byte{0x1, 0xcd, 0x1}))
+
+	if v, err := d.DecodeUInt32(); err != nil || v != 1 {
+		t.Error("wrong output", v, err)
+	}
+
+	if _, err := d.DecodeUInt32(); err != io.ErrUnexpectedEOF {
+		t.Error("expected io.ErrUnexpectedEOF", err)
+	}
+
+	d = NewDecoder(bytes.NewReader([]byte{0xc3}))
+	if _, err := d.DecodeInt64(); err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+		t.Error("expected type error", err)
+	}
+}
+
+// maxReader records the largest buffer it is asked to fill.
+type maxReader struct {
+	r   io.Reader
+	max int
+}
+
+func (r *maxReader) Read(p []byte) (int, error) {
+	if len(p) > r.max {
+		r.max = len(p)
+	}
+	return r.r.Read(p)
+}
+
+func TestDecoderLengthNotTrusted(t *testing.T) {
+	for _, b := range [][]byte{
+		{0xdb, 0xff, 0xff, 0xff, 0xf0, 0x61},
+		{0xc6, 0xff, 0xff, 0xff, 0xf0, 0x61},
+		{0xc9, 0xff, 0xff, 0xff, 0xf0, 0x1, 0x61},
+		{0xdd, 0xff, 0xff, 0xff, 0xf0, 0x1},
+	} {
+		r := &maxReader{r: bytes.NewReader(b)}
+		d := NewDecoder(r)
+		if _, err := d.DecodeValue(); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected io.ErrUnexpectedEOF for % x: %v", b, err)
+		}
+		if r.max > 4096 {
+			t.Errorf("read into %d bytes for % x", r.max, b)
+		}
+	}
+}
+
+// chunkReader returns at most n bytes from each Read, as a socket does.
+type chunkReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	return r.r.Read(p)
+}
+
+func TestDecoderLargeValue(t *testing.T) {
+	in := make([]int, 200000)
+	for i := range in {
+		in[i] = i
+	}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+	b = append(b, b...)
+
+	d := NewDecoder(&chunkReader{bytes.NewReader(b), 1400})
+	var out []int
+	if err := d.Decode(&out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Error("wrong output for Decode", len(out), err)
+	}
+	if v, err := d.DecodeValue(); err != nil || len(v.([]interface{})) != len(in) {
+		t.Error("wrong output for DecodeValue", err)
+	}
+	if err := d.Skip(); err != io.EOF {
+		t.Error("expected io.EOF", err)
+	}
+}
@@ -0,0 +1,317 @@
+package msgpack
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Marshal returns the MessagePack encoding of v.
+//
+// Booleans, integers, floats, strings and []byte use the matching Pack*
+// function, time.Time uses the timestamp extension and Ext is written with
+// PackExt. A RawMessage is copied to the output as it is, or written as nil
+// when empty. Slices and arrays become arrays and maps become maps with
+// their keys sorted where the key type allows it. Nil pointers, slices, maps
+// and interfaces are encoded as nil.
+//
+// Types in the ext registry are written as ext values with their code.
+// Other types can encode themselves by implementing CustomEncoder or
+// Marshaler, or else encoding.BinaryMarshaler, written as bin data, or
+// encoding.TextMarshaler, written as a str. Methods with a pointer receiver
+// are only used when the value is addressable.
+//
+// Structs are encoded as maps keyed by field name. The key can be changed
+// with a `msgpack:"name"` tag, or with a `json` tag when the field has no
+// msgpack tag. The tag "-" skips the field and the omitempty option leaves
+// out false, 0, nil and empty values. The fields of embedded structs are
+// promoted following the rules of encoding/json.
+//
+// Cyclic data is not supported: Marshal returns a CycleError when it runs
+// into a cycle.
+func Marshal(v interface{}) ([]byte, error) {
+	e := &Encoder{}
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "unsupported type " + e.Type.String()
+}
+
+// CycleError reports a pointer, map or slice that Marshal met again inside
+// itself.
+type CycleError struct {
+	Type reflect.Type
+}
+
+func (e *CycleError) Error() string {
+	return "encountered a cycle via " + e.Type.String()
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(RawMessage{})
+)
+
+// startDetectingCyclesAfter is how deeply pointers, maps and slices nest
+// before Marshal starts to check for cycles, as in encoding/json.
+const startDetectingCyclesAfter = 1000
+
+// sliceKey tells slices apart for the cycle check, since a slice may share
+// its first element with a shorter one.
+type sliceKey struct {
+	ptr uintptr
+	len int
+}
+
+func (e *Encoder) encodeValue(v reflect.Value) (err error) {
+	if !v.IsValid() {
+		return e.EncodeNil()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !v.IsNil() {
+			if err := e.visit(v); err != nil {
+				return err
+			}
+			defer e.leave(v)
+		}
+	}
+
+	switch v.Type() {
+	case timeType:
+		return e.EncodeTime(v.Interface().(time.Time))
+	case extType:
+		x := v.Interface().(Ext)
+		return e.EncodeExt(x.Type, x.Data)
+	case rawMessageType:
+		if v.Len() == 0 {
+			return e.EncodeNil()
+		}
+		return e.writeRaw(v.Bytes())
+	}
+
+	if x := e.ext.lookupType(v.Type()); x != nil {
+		payload, err := x.encode(v.Interface())
+		if err != nil {
+			return err
+		}
+		return e.EncodeExt(x.code, payload)
+	}
+
+	if ok, err := e.encodeCustom(v); ok {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		err = e.EncodeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		err = e.EncodeInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		err = e.EncodeUInt64(v.Uint())
+	case reflect.Float32:
+		err = e.EncodeFloat(float32(v.Float()))
+	case reflect.Float64:
+		err = e.EncodeDouble(v.Float())
+	case reflect.String:
+		err = e.EncodeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			err = e.EncodeNil()
+		} else if v.Type().Elem().Kind() == reflect.Uint8 {
+			err = e.EncodeBinary(v.Bytes())
+		} else {
+			err = e.encodeArray(v)
+		}
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			err = e.EncodeBinary(b)
+		} else {
+			err = e.encodeArray(v)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			err = e.EncodeNil()
+		} else {
+			err = e.encodeMap(v)
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			err = e.EncodeNil()
+		} else {
+			err = e.encodeValue(v.Elem())
+		}
+	case reflect.Struct:
+		err = e.encodeStruct(v)
+	default:
+		err = &UnsupportedTypeError{v.Type()}
+	}
+	return err
+}
+
+// visit counts v, a pointer, map or slice, as being encoded. Past
+// startDetectingCyclesAfter levels it also remembers v, and fails when v
+// is already being encoded further up.
+func (e *Encoder) visit(v reflect.Value) error {
+	if e.ptrLevel++; e.ptrLevel <= startDetectingCyclesAfter {
+		return nil
+	}
+
+	key := cycleKey(v)
+	if _, ok := e.ptrSeen[key]; ok {
+		e.ptrLevel--
+		return &CycleError{v.Type()}
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = make(map[interface{}]struct{})
+	}
+	e.ptrSeen[key] = struct{}{}
+	return nil
+}
+
+func (e *Encoder) leave(v reflect.Value) {
+	if e.ptrLevel > startDetectingCyclesAfter {
+		delete(e.ptrSeen, cycleKey(v))
+	}
+	e.ptrLevel--
+}
+
+func cycleKey(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Slice {
+		return sliceKey{v.Pointer(), v.Len()}
+	}
+	return v.Pointer()
+}
+
+func (e *Encoder) encodeArray(v reflect.Value) error {
+	n := v.Len()
+	if err := e.EncodeArrayHeader(uint32(n)); err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		if err := e.encodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) encodeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+	sortKeys(keys)
+
+	if err := e.EncodeMapHeader(uint32(len(keys))); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := e.encodeValue(k); err != nil {
+			return err
+		}
+		if err := e.encodeValue(v.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortKeys orders string and numeric keys so the output is deterministic.
+// Other key types are left in map order.
+func sortKeys(keys []reflect.Value) {
+	if len(keys) < 2 {
+		return
+	}
+
+	var less func(a, b reflect.Value) bool
+	switch keys[0].Kind() {
+	case reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	case reflect.Float32, reflect.Float64:
+		less = func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	default:
+		return
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+}
+
+func (e *Encoder) encodeStruct(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+
+	values := make([]reflect.Value, len(fields))
+	count := 0
+	for i := range fields {
+		fv, ok := fieldByIndex(v, fields[i].index)
+		if !ok || fields[i].omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		values[i] = fv
+		count++
+	}
+
+	if err := e.EncodeMapHeader(uint32(count)); err != nil {
+		return err
+	}
+
+	for i := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+		if err := e.EncodeString(fields[i].name); err != nil {
+			return err
+		}
+		if err := e.encodeValue(values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex that reports false instead of
+// panicking when it meets a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
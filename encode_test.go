@@ -0,0 +1,111 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMarshalScalars(t *testing.T) {
+	var nilPtr *int
+	one := 1
+
+	for _, c := range []struct {
+		v        interface{}
+		expected []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{int8(-1), []byte{0xff}},
+		{int64(-129), []byte{0xd1, 0xff, 0x7f}},
+		{uint16(200), []byte{0xcc, 0xc8}},
+		{float32(3.1415926), []byte{0xca, 0x40, 0x49, 0xf, 0xda}},
+		{3.1416786785926, []byte{0xcb, 0x40, 0x9, 0x22, 0x28, 0x6e, 0x58, 0xc4, 0x5}},
+		{"hi", []byte{0xa2, 0x68, 0x69}},
+		{[]byte{0x1, 0x2}, []byte{0xc4, 0x2, 0x1, 0x2}},
+		{[2]byte{0x1, 0x2}, []byte{0xc4, 0x2, 0x1, 0x2}},
+		{nilPtr, []byte{0xc0}},
+		{&one, []byte{0x1}},
+		{[]int(nil), []byte{0xc0}},
+		{[]int{1, -1}, []byte{0x92, 0x1, 0xff}},
+		{[3]string{"a"}, []byte{0x93, 0xa1, 0x61, 0xa0, 0xa0}},
+		{[]interface{}{nil, "a", 1}, []byte{0x93, 0xc0, 0xa1, 0x61, 0x1}},
+		{map[string]int(nil), []byte{0xc0}},
+		{map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 0x61, 0x1, 0xa1, 0x62, 0x2}},
+		{map[int]bool{3: true, -1: false}, []byte{0x82, 0xff, 0xc2, 0x3, 0xc3}},
+		{time.Unix(1, 0), []byte{0xd6, 0xff, 0x0, 0x0, 0x0, 0x1}},
+	} {
+		b, err := Marshal(c.v)
+		if err != nil || bytes.Compare(b, c.expected) != 0 {
+			t.Errorf("wrong output for %#v: % x %v", c.v, b, err)
+		}
+	}
+}
+
+type marshalInner struct {
+	X int
+}
+
+type marshalStruct struct {
+	Name    string
+	Tags    []string
+	Inner   *marshalInner
+	private int
+}
+
+func TestMarshalStruct(t *testing.T) {
+	b, err := Marshal(marshalStruct{Name: "a", Inner: &marshalInner{X: 1}, private: 5})
+	if err != nil {
+		t.Error("err != nil", err)
+	}
+
+	if bytes.Compare(b, []byte{0x83,
+		0xa4, 0x4e, 0x61, 0x6d, 0x65, 0xa1, 0x61,
+		0xa4, 0x54, 0x61, 0x67, 0x73, 0xc0,
+		0xa5, 0x49, 0x6e, 0x6e, 0x65, 0x72, 0x81, 0xa1, 0x58, 0x1}) != 0 {
+		t.Errorf("wrong output % x", b)
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	_, err := Marshal(map[string]interface{}{"f": func() {}})
+	if _, ok := err.(*UnsupportedTypeError); !ok {
+		t.Error("expected UnsupportedTypeError", err)
+	}
+}
+
+type cycleNode struct {
+	Next *cycleNode
+	More []interface{}
+}
+
+func TestMarshalCycle(t *testing.T) {
+	n := &cycleNode{}
+	n.Next = n
+	if _, err := Marshal(n); !errors.As(err, new(*CycleError)) {
+		t.Error("expected CycleError for pointer cycle", err)
+	}
+
+	s := []interface{}{nil}
+	s[0] = s
+	if _, err := Marshal(s); !errors.As(err, new(*CycleError)) {
+		t.Error("expected CycleError for slice cycle", err)
+	}
+
+	m := map[string]interface{}{}
+	m["m"] = m
+	if _, err := Marshal(m); !errors.As(err, new(*CycleError)) {
+		t.Error("expected CycleError for map cycle", err)
+	}
+
+	// Values met twice without a cycle are fine, however deep.
+	shared := &cycleNode{}
+	deep := &cycleNode{More: []interface{}{shared, shared}}
+	for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+		deep = &cycleNode{Next: deep}
+	}
+	if _, err := Marshal([]*cycleNode{deep, deep}); err != nil {
+		t.Error("err != nil", err)
+	}
+}
@@ -0,0 +1,261 @@
+package msgpack
+
+import (
+	"io"
+	"reflect"
+	"time"
+)
+
+// An Encoder writes MessagePack values to an output stream through an
+// internal buffer. The first error it meets is kept and returned by every
+// later call, so a sequence of Encode calls can be checked once at the end.
+// Call Flush to write out what is still buffered.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+	n   int64 // bytes flushed
+	err error
+	ext *ExtRegistry
+
+	ptrLevel uint                     // pointers, maps and slices being encoded
+	ptrSeen  map[interface{}]struct{} // those of them past startDetectingCyclesAfter
+}
+
+const encoderBufferSize = 4096
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetExtRegistry makes Encode use r for ext types instead of the registry
+// of RegisterExt.
+func (e *Encoder) SetExtRegistry(r *ExtRegistry) {
+	e.ext = r
+}
+
+// Flush writes the buffered data to the underlying writer.
+func (e *Encoder) Flush() error {
+	if e.err != nil || e.w == nil {
+		return e.err
+	}
+
+	n, err := e.w.Write(e.buf)
+	e.n += int64(n)
+	if err != nil {
+		e.err = err
+	}
+	e.buf = e.buf[:0]
+	return e.err
+}
+
+// Count returns the number of bytes encoded so far, flushed or not.
+func (e *Encoder) Count() int64 {
+	return e.n + int64(len(e.buf))
+}
+
+// Buffered returns the number of bytes waiting for Flush.
+func (e *Encoder) Buffered() int {
+	return len(e.buf)
+}
+
+// Err returns the first error met by the Encoder.
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+func (e *Encoder) flushIfFull() error {
+	if e.w != nil && len(e.buf) >= encoderBufferSize {
+		return e.Flush()
+	}
+	return nil
+}
+
+// Encode writes the MessagePack encoding of v, following the rules of
+// Marshal.
+func (e *Encoder) Encode(v interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if err := e.encodeValue(reflect.ValueOf(v)); err != nil && e.err == nil {
+		e.err = err
+	}
+	return e.err
+}
+
+// writeRaw copies an already encoded value to the output.
+func (e *Encoder) writeRaw(b []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = append(e.buf, b...)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeUInt64(value uint64) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendUInt64(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeUInt32(value uint32) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendUInt32(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeInt64(value int64) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendInt64(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeInt32(value int32) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendInt32(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeUInt16(value uint16) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendUInt16(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeInt16(value int16) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendInt16(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeUInt8(value uint8) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendUInt8(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeInt8(value int8) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendInt8(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeUInt(value uint) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendUInt(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeInt(value int) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendInt(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeNil() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendNil(e.buf)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeBool(value bool) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendBool(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeFloat(value float32) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendFloat(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeDouble(value float64) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendDouble(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeRawBuffer(value []uint8) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendRawBuffer(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeString(value string) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendString(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeBinary(value []uint8) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendBinary(e.buf, value)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeArrayHeader(length uint32) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendArrayHeader(e.buf, length)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeMapHeader(length uint32) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendMapHeader(e.buf, length)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeExt(typeCode int8, payload []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendExt(e.buf, typeCode, payload)
+	return e.flushIfFull()
+}
+
+func (e *Encoder) EncodeTime(value time.Time) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = AppendTime(e.buf, value)
+	return e.flushIfFull()
+}
@@ -0,0 +1,119 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestEncoder(t *testing.T) {
+	w := &countingWriter{}
+	e := NewEncoder(w)
+
+	e.EncodeInt64(-129)
+	e.EncodeUInt64(18446744073709551615)
+	e.EncodeInt32(2147483647)
+	e.EncodeUInt32(65536)
+	e.EncodeNil()
+	e.EncodeBool(true)
+	e.EncodeFloat(3.1415926)
+	e.EncodeDouble(3.1416786785926)
+	e.EncodeRawBuffer([]byte("raw"))
+	e.EncodeString("hello world")
+	e.EncodeBinary([]byte{0x1, 0x2})
+	e.EncodeArrayHeader(20)
+	e.EncodeMapHeader(70000)
+	e.EncodeExt(5, []byte{0x1, 0x2, 0x3})
+	e.EncodeTime(time.Unix(1, 0))
+	e.Encode(map[string]int{"a": 1})
+
+	if w.writes != 0 {
+		t.Error("encoder did not buffer", w.writes)
+	}
+
+	if err := e.Flush(); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	expected := []byte{0xd1, 0xff, 0x7f,
+		0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xd2, 0x7f, 0xff, 0xff, 0xff,
+		0xce, 0x0, 0x1, 0x0, 0x0,
+		0xc0, 0xc3,
+		0xca, 0x40, 0x49, 0xf, 0xda,
+		0xcb, 0x40, 0x9, 0x22, 0x28, 0x6e, 0x58, 0xc4, 0x5,
+		0xa3, 0x72, 0x61, 0x77,
+		0xab, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64,
+		0xc4, 0x2, 0x1, 0x2,
+		0xdc, 0x0, 0x14,
+		0xdf, 0x0, 0x1, 0x11, 0x70,
+		0xc7, 0x3, 0x5, 0x1, 0x2, 0x3,
+		0xd6, 0xff, 0x0, 0x0, 0x0, 0x1,
+		0x81, 0xa1, 0x61, 0x1}
+
+	if w.writes != 1 || bytes.Compare(w.Bytes(), expected) != 0 {
+		t.Errorf("wrong output %d % x", w.writes, w.Bytes())
+	}
+
+	if e.Count() != int64(len(expected)) || e.Buffered() != 0 {
+		t.Error("wrong count", e.Count(), e.Buffered())
+	}
+}
+
+func TestEncoderAutoFlush(t *testing.T) {
+	w := &countingWriter{}
+	e := NewEncoder(w)
+
+	for i := 0; i < 3; i++ {
+		e.EncodeBinary(make([]byte, encoderBufferSize))
+	}
+
+	if w.writes != 3 || e.Buffered() != 0 {
+		t.Error("wrong number of writes", w.writes, e.Buffered())
+	}
+
+	if e.Count() != 3*(encoderBufferSize+3) || e.Flush() != nil {
+		t.Error("wrong count", e.Count())
+	}
+}
+
+type failingWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestEncoderStickyError(t *testing.T) {
+	e := NewEncoder(failingWriter{})
+
+	e.EncodeBinary(make([]byte, encoderBufferSize))
+	if e.Err() != errWrite {
+		t.Error("expected write error", e.Err())
+	}
+
+	if e.EncodeInt64(1) != errWrite || e.Encode("a") != errWrite || e.Flush() != errWrite {
+		t.Error("error is not sticky")
+	}
+
+	e = NewEncoder(&bytes.Buffer{})
+	if _, ok := e.Encode(func() {}).(*UnsupportedTypeError); !ok {
+		t.Error("expected UnsupportedTypeError", e.Err())
+	}
+
+	if _, ok := e.EncodeNil().(*UnsupportedTypeError); !ok {
+		t.Error("error is not sticky")
+	}
+}
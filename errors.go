@@ -0,0 +1,91 @@
+package msgpack
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// ErrUnpackOverflow is matched by every TruncatedError through errors.Is.
+var ErrUnpackOverflow = errors.New("unpack overflow")
+
+// TypeMismatchError reports a header that is not one of the formats the
+// caller asked for.
+type TypeMismatchError struct {
+	Offset   int
+	Header   uint8
+	Expected string
+}
+
+func (e *TypeMismatchError) Error() string {
+	return "invalid type header 0x" + strconv.FormatUint(uint64(e.Header), 16) +
+		" at offset " + strconv.Itoa(e.Offset) + ", expected " + e.Expected
+}
+
+// TruncatedError reports a value that runs past the end of the buffer.
+// Needed and Have count bytes from Offset, where the value starts.
+type TruncatedError struct {
+	Offset int
+	Needed int
+	Have   int
+}
+
+func (e *TruncatedError) Error() string {
+	return "unpack overflow: value at offset " + strconv.Itoa(e.Offset) + " needs " +
+		strconv.Itoa(e.Needed) + " bytes, have " + strconv.Itoa(e.Have)
+}
+
+func (e *TruncatedError) Is(target error) bool {
+	return target == ErrUnpackOverflow
+}
+
+// OverflowError reports a number that was read fine but does not fit the
+// type it was unpacked into. Value holds it as an int64, uint64 or float64.
+type OverflowError struct {
+	Offset int
+	Value  interface{}
+	Target string
+}
+
+func (e *OverflowError) Error() string {
+	var s string
+	switch v := e.Value.(type) {
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return "value " + s + " at offset " + strconv.Itoa(e.Offset) + " does not fit in " + e.Target
+}
+
+// MaxDepth is how deeply arrays and maps may nest in a value decoded into
+// interface{}, through reflection or by code from msgpackgen, as in
+// encoding/json.
+const MaxDepth = 10000
+
+// DepthError reports an array or map nested more than 10000 levels deep.
+type DepthError struct {
+	Offset int
+}
+
+func (e *DepthError) Error() string {
+	return "value at offset " + strconv.Itoa(e.Offset) + " is nested more than " +
+		strconv.Itoa(MaxDepth) + " levels deep"
+}
+
+func mismatch(buf []byte, start int, expected string) error {
+	return &TypeMismatchError{Offset: start, Header: buf[start], Expected: expected}
+}
+
+func truncated(buf []byte, start int, needed uint64) error {
+	have := 0
+	if start < len(buf) {
+		have = len(buf) - start
+	}
+	if needed > math.MaxInt32 && strconv.IntSize == 32 {
+		needed = math.MaxInt32
+	}
+	return &TruncatedError{Offset: start, Needed: int(needed), Have: have}
+}
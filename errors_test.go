@@ -0,0 +1,84 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTypeMismatchError(t *testing.T) {
+	b := []byte{0xc0, 0xa1, 0x61}
+	offset := uint32(1)
+	_, err := UnpackUInt64(b, &offset)
+
+	var e *TypeMismatchError
+	if !errors.As(err, &e) {
+		t.Fatal("wrong error", err)
+	}
+	if e.Offset != 1 || e.Header != 0xa1 || e.Expected != "uint64" {
+		t.Error("wrong fields", e)
+	}
+	if err.Error() != "invalid type header 0xa1 at offset 1, expected uint64" {
+		t.Error("wrong message", err.Error())
+	}
+	if errors.Is(err, ErrUnpackOverflow) {
+		t.Error("mismatch matched ErrUnpackOverflow")
+	}
+}
+
+func TestTruncatedError(t *testing.T) {
+	b := []byte{0xc0, 0xcd, 0x1}
+	offset := uint32(1)
+	_, err := UnpackUInt32(b, &offset)
+
+	var e *TruncatedError
+	if !errors.As(err, &e) {
+		t.Fatal("wrong error", err)
+	}
+	if e.Offset != 1 || e.Needed != 3 || e.Have != 2 {
+		t.Error("wrong fields", e)
+	}
+	if !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("truncation did not match ErrUnpackOverflow")
+	}
+}
+
+func TestUnpackErrorsConsistent(t *testing.T) {
+	offset := uint32(0)
+	if _, err := UnpackString([]byte{0x1}, &offset); !isMismatch(err, 0x1) {
+		t.Error("wrong error", err)
+	}
+
+	offset = 0
+	if _, _, err := UnpackExt([]byte{0xc0}, &offset); !isMismatch(err, 0xc0) {
+		t.Error("wrong error", err)
+	}
+
+	b := &bytes.Buffer{}
+	PackExt(b, 5, []byte{1, 2, 3, 4})
+	offset = 0
+	if _, err := UnpackTime(b.Bytes(), &offset); !isMismatch(err, 0xd6) {
+		t.Error("wrong error", err)
+	}
+
+	offset = 0
+	if _, err := UnpackBool(nil, &offset); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("wrong error", err)
+	}
+
+	offset = 0
+	if _, err := UnpackTime([]byte{0xd6, 0xff}, &offset); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("wrong error", err)
+	}
+
+	var v time.Time
+	if err := Unmarshal([]byte{0xa0}, &v); !isMismatch(err, 0xa0) {
+		t.Error("wrong error", err)
+	}
+}
+
+func isMismatch(err error, header uint8) bool {
+	var e *TypeMismatchError
+	return errors.As(err, &e) && e.Header == header
+}
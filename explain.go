@@ -0,0 +1,260 @@
+package msgpack
+
+import (
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	explainBytes  = 8  // bytes shown on a line before it is cut short
+	explainString = 32 // bytes of a raw value quoted on a line
+)
+
+// Explain writes buf to w header by header, one line for each value: its
+// offset, its bytes, how deep it is nested, the name of its format and the
+// decoded value. Bad data does not stop it: a header that is not valid is
+// marked and skipped, as is a timestamp that does not decode, and data cut
+// short is marked where it starts. Explain returns the first such error,
+// or the error writing to w.
+func Explain(buf []byte, w io.Writer) error {
+	c := NewCursor(buf)
+	var first error
+	var remaining []uint64 // values left in each open container
+
+	line := appendPadded([]byte(" "), "offset", 7)
+	line = appendPadded(line, "depth", 7)
+	line = appendPadded(line, "bytes", 28)
+	line = appendPadded(line, "format", 16)
+	line = append(line, "value\n"...)
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+
+	for c.off < len(buf) {
+		start := c.off
+		depth := len(remaining)
+		value, n, err := c.explainValue()
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			if c.off == start {
+				// Only a header that is not valid and data cut short are
+				// not consumed; the first is one byte and the second all
+				// that is left.
+				if typeOfHeader(buf[start]) == InvalidType {
+					c.off++
+				} else {
+					c.off = len(buf)
+				}
+			}
+			value = "error: " + err.Error()
+		}
+
+		line = appendExplainLine(line[:0], buf[start:c.off], start, depth, formatName(buf[start]), value, err != nil)
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+
+		if len(remaining) > 0 {
+			remaining[len(remaining)-1]--
+		}
+		if n > 0 {
+			remaining = append(remaining, n)
+		}
+		for len(remaining) > 0 && remaining[len(remaining)-1] == 0 {
+			remaining = remaining[:len(remaining)-1]
+		}
+	}
+
+	if len(remaining) > 0 {
+		var missing uint64
+		for _, n := range remaining {
+			missing += n
+		}
+		if first == nil {
+			first = truncated(buf, len(buf), 1)
+		}
+		value := "error: data ends " + strconv.FormatUint(missing, 10) + " values short"
+		line = appendExplainLine(line[:0], nil, len(buf), len(remaining), "", value, true)
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return first
+}
+
+// explainValue unpacks the next header and describes it. For an array or a
+// map it also returns the number of values the container holds.
+func (c *Cursor) explainValue() (value string, n uint64, err error) {
+	off := c.off
+	switch typeOfHeader(c.buf[off]) {
+	case NilType:
+		c.off++
+		return "nil", 0, nil
+	case BoolType:
+		v, err := c.UnpackBool()
+		return strconv.FormatBool(v), 0, err
+	case IntType:
+		v, err := c.UnpackInt64()
+		return strconv.FormatInt(v, 10), 0, err
+	case UintType:
+		v, err := c.UnpackUInt64()
+		return strconv.FormatUint(v, 10), 0, err
+	case Float32Type:
+		v, err := c.UnpackFloat()
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), 0, err
+	case Float64Type:
+		v, err := c.UnpackDouble()
+		return strconv.FormatFloat(v, 'g', -1, 64), 0, err
+	case RawType:
+		v, err := c.UnpackRawBuffer()
+		if len(v) > explainString {
+			return strconv.Quote(string(v[:explainString])) + "... len " + strconv.Itoa(len(v)), 0, err
+		}
+		return strconv.Quote(string(v)), 0, err
+	case BinType:
+		v, err := c.UnpackRawBuffer()
+		return "len " + strconv.Itoa(len(v)), 0, err
+	case ArrayType:
+		l, err := c.UnpackArrayHeader()
+		return "len " + strconv.FormatUint(uint64(l), 10), uint64(l), err
+	case MapType:
+		l, err := c.UnpackMapHeader()
+		return "len " + strconv.FormatUint(uint64(l), 10), 2 * uint64(l), err
+	case ExtType:
+		code, payload, err := c.UnpackExt()
+		if err != nil || code != MP_EXT_TIMESTAMP {
+			return "type " + strconv.Itoa(int(code)) + " len " + strconv.Itoa(len(payload)), 0, err
+		}
+		t, err := decodeTimestamp(c.buf, off, payload)
+		return "timestamp " + t.Format(time.RFC3339Nano), 0, err
+	}
+	return "", 0, mismatch(c.buf, off, "any type")
+}
+
+// formatName returns the name of the format a header starts, after the
+// MP_* constant for it.
+func formatName(header uint8) string {
+	switch {
+	case header <= MAX_7BIT:
+		return "fixnum"
+	case header >= MP_NEGATIVE_FIXNUM:
+		return "negative fixnum"
+	case header&0xF0 == MP_FIXMAP:
+		return "fixmap"
+	case header&0xF0 == MP_FIXARRAY:
+		return "fixarray"
+	case header&0xE0 == MP_FIXRAW:
+		return "fixraw"
+	}
+
+	switch header {
+	case MP_NULL:
+		return "nil"
+	case MP_FALSE:
+		return "false"
+	case MP_TRUE:
+		return "true"
+	case MP_BIN8:
+		return "bin8"
+	case MP_BIN16:
+		return "bin16"
+	case MP_BIN32:
+		return "bin32"
+	case MP_EXT8:
+		return "ext8"
+	case MP_EXT16:
+		return "ext16"
+	case MP_EXT32:
+		return "ext32"
+	case MP_FLOAT:
+		return "float"
+	case MP_DOUBLE:
+		return "double"
+	case MP_UINT8:
+		return "uint8"
+	case MP_UINT16:
+		return "uint16"
+	case MP_UINT32:
+		return "uint32"
+	case MP_UINT64:
+		return "uint64"
+	case MP_INT8:
+		return "int8"
+	case MP_INT16:
+		return "int16"
+	case MP_INT32:
+		return "int32"
+	case MP_INT64:
+		return "int64"
+	case MP_FIXEXT1:
+		return "fixext1"
+	case MP_FIXEXT2:
+		return "fixext2"
+	case MP_FIXEXT4:
+		return "fixext4"
+	case MP_FIXEXT8:
+		return "fixext8"
+	case MP_FIXEXT16:
+		return "fixext16"
+	case MP_STR8:
+		return "str8"
+	case MP_RAW16:
+		return "raw16"
+	case MP_RAW32:
+		return "raw32"
+	case MP_ARRAY16:
+		return "array16"
+	case MP_ARRAY32:
+		return "array32"
+	case MP_MAP16:
+		return "map16"
+	case MP_MAP32:
+		return "map32"
+	}
+	return "invalid"
+}
+
+// appendExplainLine writes one line of Explain. The format is indented by
+// the depth and a line with an error starts with '!'.
+func appendExplainLine(dst []byte, b []byte, offset, depth int, format, value string, bad bool) []byte {
+	mark := byte(' ')
+	if bad {
+		mark = '!'
+	}
+	dst = append(dst, mark)
+	dst = appendPadded(dst, strconv.Itoa(offset), 7)
+	dst = appendPadded(dst, strconv.Itoa(depth), 7)
+
+	width := len(dst) + 28
+	for i, x := range b {
+		if i == explainBytes {
+			dst = append(dst, " .."...)
+			break
+		}
+		if i > 0 {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, hexDigits[x>>4], hexDigits[x&0xF])
+	}
+	for len(dst) < width {
+		dst = append(dst, ' ')
+	}
+
+	for i := 0; i < depth; i++ {
+		dst = append(dst, "  "...)
+	}
+	dst = appendPadded(dst, format, 16)
+	dst = append(dst, value...)
+	return append(dst, '\n')
+}
+
+func appendPadded(dst []byte, s string, width int) []byte {
+	dst = append(dst, s...)
+	for i := len(s); i < width; i++ {
+		dst = append(dst, ' ')
+	}
+	return dst
+}
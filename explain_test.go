@@ -0,0 +1,98 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+	b := AppendMapHeader(nil, 2)
+	b = AppendString(b, "id")
+	b = AppendUInt64(b, 300)
+	b = AppendString(b, "tags")
+	b = AppendArrayHeader(b, 2)
+	b = AppendBinary(b, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	b = AppendDouble(b, -1.5)
+	b = AppendTime(b, time.Unix(1, 5))
+	b = AppendInt64(b, -3)
+
+	var out bytes.Buffer
+	if err := Explain(b, &out); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	want := ` offset depth  bytes                       format          value
+ 0      0      82                          fixmap          len 2
+ 1      1      a2 69 64                      fixraw          "id"
+ 4      1      cd 01 2c                      uint16          300
+ 7      1      a4 74 61 67 73                fixraw          "tags"
+ 12     1      92                            fixarray        len 2
+ 13     2      c4 08 01 02 03 04 05 06 ..      bin8            len 8
+ 23     2      cb bf f8 00 00 00 00 00 ..      double          -1.5
+ 32     0      d7 ff 00 00 00 14 00 00 ..  fixext8         timestamp 1970-01-01T00:00:01.000000005Z
+ 42     0      fd                          negative fixnum -3
+`
+	if out.String() != want {
+		t.Error("wrong output\n" + out.String())
+	}
+}
+
+func TestExplainErrors(t *testing.T) {
+	// The invalid header is skipped and the array still ends after it.
+	var out bytes.Buffer
+	err := Explain([]byte{0x92, 0xc1, 0x1, 0xc0}, &out)
+	var mismatchErr *TypeMismatchError
+	if !errors.As(err, &mismatchErr) || mismatchErr.Offset != 1 {
+		t.Error("wrong error", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[2], "!1      1      c1") ||
+		!strings.Contains(lines[3], " 1  ") || !strings.HasPrefix(lines[4], " 3      0      c0") {
+		t.Error("wrong output\n" + out.String())
+	}
+
+	// A timestamp that does not decode is skipped as a whole.
+	out.Reset()
+	err = Explain([]byte{0xd4, 0xff, 0x0, 0xc3}, &out)
+	if !errors.As(err, &mismatchErr) || mismatchErr.Offset != 0 ||
+		!strings.Contains(out.String(), "!0      0      d4 ff 00") || !strings.Contains(out.String(), " 3      0      c3") {
+		t.Error("wrong output\n"+out.String(), err)
+	}
+
+	// Data cut short is marked where the value starts.
+	out.Reset()
+	err = Explain([]byte{0x93, 0xa1, 'a', 0xcd, 0x1}, &out)
+	var truncErr *TruncatedError
+	if !errors.As(err, &truncErr) || truncErr.Offset != 3 ||
+		!strings.Contains(out.String(), "!3      1      cd 01") ||
+		!strings.Contains(out.String(), "!5      1") || !strings.Contains(out.String(), "data ends 1 values short") {
+		t.Error("wrong output\n"+out.String(), err)
+	}
+
+	out.Reset()
+	err = Explain([]byte{0x81, 0xc0}, &out)
+	if !errors.Is(err, ErrUnpackOverflow) || !strings.Contains(out.String(), "!2      1") {
+		t.Error("wrong output\n"+out.String(), err)
+	}
+}
+
+type failWriter struct{ n int }
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestExplainWriteError(t *testing.T) {
+	for n := 0; n < 3; n++ {
+		if err := Explain([]byte{0x91, 0x1}, &failWriter{n}); err == nil || err.Error() != "write failed" {
+			t.Error("wrong error", n, err)
+		}
+	}
+}
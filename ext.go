@@ -0,0 +1,70 @@
+package msgpack
+
+import (
+	"io"
+	"reflect"
+)
+
+// Ext holds an extension value whose type code has no Go mapping.
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+var extType = reflect.TypeOf(Ext{})
+
+func PackExt(writer io.Writer, typeCode int8, payload []byte) (count int, err error) {
+	var b [6]byte
+	return packWithHeader(writer, appendExtHeader(b[:0], typeCode, uint64(len(payload))), payload)
+}
+
+// UnpackExt returns the payload as a slice of the buffer, like
+// UnpackRawBuffer.
+func (c *Cursor) UnpackExt() (typeCode int8, payload []byte, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	header, err := c.header()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var length uint32
+	switch header {
+	case MP_FIXEXT1:
+		length = 1
+	case MP_FIXEXT2:
+		length = 2
+	case MP_FIXEXT4:
+		length = 4
+	case MP_FIXEXT8:
+		length = 8
+	case MP_FIXEXT16:
+		length = 16
+	case MP_EXT8:
+		length, err = c.length(start, 1)
+	case MP_EXT16:
+		length, err = c.length(start, 2)
+	case MP_EXT32:
+		length, err = c.length(start, 4)
+	default:
+		return 0, nil, mismatch(c.buf, start, "ext")
+	}
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	off := c.off
+	if err = c.need(start, 1+uint64(length)); err != nil {
+		return 0, nil, err
+	}
+	return int8(c.buf[off]), c.buf[off+1 : off+1+int(length)], nil
+}
+
+func UnpackExt(buf []byte, offset *uint32) (typeCode int8, payload []byte, err error) {
+	c := cursorAt(buf, offset)
+	typeCode, payload, err = c.UnpackExt()
+	*offset = uint32(c.off)
+	return typeCode, payload, err
+}
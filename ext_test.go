@@ -0,0 +1,73 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPackExt(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	for _, i := range [][]byte{{0x1}, {0x1, 0x2}, {0x1, 0x2, 0x3, 0x4},
+		{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+		bytes.Repeat([]byte{0xaa}, 16), {}, {0x1, 0x2, 0x3}, bytes.Repeat([]byte{0xbb}, 256)} {
+		_, err := PackExt(b, 5, i)
+		if err != nil {
+			t.Error("err != nil")
+		}
+	}
+
+	expected := []byte{0xd4, 0x5, 0x1, 0xd5, 0x5, 0x1, 0x2, 0xd6, 0x5, 0x1, 0x2, 0x3, 0x4,
+		0xd7, 0x5, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0xd8, 0x5}
+	expected = append(expected, bytes.Repeat([]byte{0xaa}, 16)...)
+	expected = append(expected, 0xc7, 0x0, 0x5, 0xc7, 0x3, 0x5, 0x1, 0x2, 0x3, 0xc8, 0x1, 0x0, 0x5)
+	expected = append(expected, bytes.Repeat([]byte{0xbb}, 256)...)
+
+	if bytes.Compare(b.Bytes(), expected) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+
+	b.Reset()
+	n, err := PackExt(b, -1, []byte{0x1, 0x2, 0x3})
+	if err != nil || n != 6 || bytes.Compare(b.Bytes(), []byte{0xc7, 0x3, 0xff, 0x1, 0x2, 0x3}) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+}
+
+func TestUnpackExt(t *testing.T) {
+	b := []byte{0xd4, 0x5, 0x1, 0xd5, 0x5, 0x1, 0x2, 0xd6, 0x5, 0x1, 0x2, 0x3, 0x4,
+		0xd7, 0x5, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0xd8, 0x5}
+	b = append(b, bytes.Repeat([]byte{0xaa}, 16)...)
+	b = append(b, 0xc7, 0x0, 0x5, 0xc7, 0x3, 0xff, 0x1, 0x2, 0x3, 0xc8, 0x1, 0x0, 0x5)
+	b = append(b, bytes.Repeat([]byte{0xbb}, 256)...)
+	b = append(b, 0xc9, 0x0, 0x0, 0x0, 0x1, 0x80, 0x9)
+
+	v := [][]byte{{0x1}, {0x1, 0x2}, {0x1, 0x2, 0x3, 0x4},
+		{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+		bytes.Repeat([]byte{0xaa}, 16), {}, {0x1, 0x2, 0x3}, bytes.Repeat([]byte{0xbb}, 256), {0x9}}
+	codes := []int8{5, 5, 5, 5, 5, 5, -1, 5, -128}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		code, val, err := UnpackExt(b, &offset)
+		if err != nil || code != codes[i] || bytes.Compare(val, v[i]) != 0 {
+			t.Error("wrong output", i)
+		}
+	}
+
+	if int(offset) != len(b) {
+		t.Error("wrong offset", offset)
+	}
+
+	offset = 0
+	if _, _, err := UnpackExt([]byte{0xd6, 0x5, 0x1, 0x2}, &offset); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("expected overflow", err)
+	}
+
+	offset = 0
+	if _, _, err := UnpackExt([]byte{0xc4, 0x0}, &offset); err == nil {
+		t.Error("expected error for bin")
+	}
+}
@@ -0,0 +1,173 @@
+package msgpack
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// cachedFields returns the fields of the struct type t that are encoded,
+// including those promoted from embedded structs, in field order.
+func cachedFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.([]field)
+}
+
+// structTag returns the msgpack tag of sf, falling back to its json tag.
+func structTag(sf reflect.StructField) (tag string, ok bool) {
+	if tag, ok = sf.Tag.Lookup("msgpack"); ok {
+		return tag, ok
+	}
+	return sf.Tag.Lookup("json")
+}
+
+func parseTag(tag string) (name string, omitEmpty bool) {
+	name, opts, _ := strings.Cut(tag, ",")
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
+
+// typeFields walks t breadth first like encoding/json, so that a field
+// hides any field of the same name at a deeper level of embedding.
+func typeFields(t reflect.Type) []field {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []field
+	current := []embedded{}
+	next := []embedded{{typ: t}}
+
+	// count and nextCount say how many times a struct type is embedded at
+	// the current and next level. The fields of a type embedded more than
+	// once at a level are recorded twice so that dominantField drops them.
+	count := map[reflect.Type]int{}
+	nextCount := map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				if sf.Anonymous {
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+
+				tag, _ := structTag(sf)
+				if tag == "-" {
+					continue
+				}
+				name, omitEmpty := parseTag(tag)
+
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct && ft != timeType {
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, embedded{typ: ft, index: index})
+					}
+					continue
+				}
+
+				tagged := name != ""
+				if name == "" {
+					name = sf.Name
+				}
+				fields = append(fields, field{
+					name:      name,
+					index:     index,
+					omitEmpty: omitEmpty,
+					tagged:    tagged,
+				})
+				if count[e.typ] > 1 {
+					fields = append(fields, fields[len(fields)-1])
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		if len(fields[i].index) != len(fields[j].index) {
+			return len(fields[i].index) < len(fields[j].index)
+		}
+		return fields[i].tagged && !fields[j].tagged
+	})
+
+	out := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if f, ok := dominantField(fields[i:j]); ok {
+			out = append(out, f)
+		}
+		i = j
+	}
+	fields = out
+
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// dominantField picks the field that wins among fields sharing a name,
+// which are sorted by depth and then tagged first. Ambiguous names are
+// dropped altogether.
+func dominantField(fields []field) (field, bool) {
+	if len(fields) > 1 && len(fields[0].index) == len(fields[1].index) &&
+		fields[0].tagged == fields[1].tagged {
+		return field{}, false
+	}
+	return fields[0], true
+}
@@ -0,0 +1,133 @@
+package msgpack
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type tagBase struct {
+	ID    int `msgpack:"id"`
+	Shade string
+}
+
+type tagOther struct {
+	Shade string
+	Depth int
+}
+
+type tagStruct struct {
+	tagBase
+	*tagOther
+	Name     string `msgpack:"name,omitempty"`
+	JSONName int    `json:"json_name"`
+	Both     int    `msgpack:"both" json:"ignored"`
+	Skip     int    `msgpack:"-"`
+	Dash     int    `msgpack:"-,"`
+	Empty    []int  `json:",omitempty"`
+	Depth    int
+}
+
+func TestCachedFields(t *testing.T) {
+	var names []string
+	for _, f := range cachedFields(reflect.TypeOf(tagStruct{})) {
+		names = append(names, f.name)
+	}
+
+	// Shade is ambiguous and tagStruct.Depth hides tagOther.Depth.
+	expected := []string{"id", "name", "json_name", "both", "-", "Empty", "Depth"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Error("wrong output", names)
+	}
+}
+
+type twiceInner struct {
+	X int
+}
+
+type twiceA struct {
+	twiceInner
+}
+
+type twiceB struct {
+	twiceInner
+}
+
+type twiceStruct struct {
+	twiceA
+	twiceB
+	Y int
+}
+
+func TestFieldsEmbeddedTwice(t *testing.T) {
+	// X comes from twiceInner through both twiceA and twiceB, so it is
+	// ambiguous and left out, as encoding/json does.
+	b, err := Marshal(twiceStruct{twiceA{twiceInner{1}}, twiceB{twiceInner{2}}, 3})
+	if err != nil || bytes.Compare(b, []byte{0x81, 0xa1, 'Y', 0x3}) != 0 {
+		t.Errorf("wrong output % x %v", b, err)
+	}
+}
+
+func TestMarshalTags(t *testing.T) {
+	b, err := Marshal(tagStruct{tagBase: tagBase{ID: 1}, JSONName: 2, Skip: 3, Dash: 4})
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if bytes.Compare(b, []byte{0x85,
+		0xa2, 0x69, 0x64, 0x1,
+		0xa9, 0x6a, 0x73, 0x6f, 0x6e, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x2,
+		0xa4, 0x62, 0x6f, 0x74, 0x68, 0x0,
+		0xa1, 0x2d, 0x4,
+		0xa5, 0x44, 0x65, 0x70, 0x74, 0x68, 0x0}) != 0 {
+		t.Errorf("wrong output % x", b)
+	}
+}
+
+type TagPromoted struct {
+	Depth int
+}
+
+type tagOuter struct {
+	*TagPromoted
+	Name string `json:"name"`
+}
+
+func TestUnmarshalTags(t *testing.T) {
+	in := tagStruct{tagBase: tagBase{ID: 1}, Name: "n", JSONName: 2, Both: 3, Skip: 4, Dash: 5,
+		Empty: []int{6}, Depth: 7}
+
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	var out tagStruct
+	if err = Unmarshal(b, &out); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	in.Skip = 0
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("wrong output %#v", out)
+	}
+
+	b, err = Marshal(tagOuter{TagPromoted: &TagPromoted{Depth: 1}, Name: "a"})
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	var outer tagOuter
+	if err = Unmarshal(b, &outer); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if outer.TagPromoted == nil || outer.Depth != 1 || outer.Name != "a" {
+		t.Errorf("wrong output %#v", outer)
+	}
+
+	b, err = Marshal(tagOuter{Name: "a"})
+	if err != nil || bytes.Compare(b, []byte{0x81, 0xa4, 0x6e, 0x61, 0x6d, 0x65, 0xa1, 0x61}) != 0 {
+		t.Errorf("wrong output for nil embedded pointer % x", b)
+	}
+}
@@ -0,0 +1,154 @@
+package msgpack
+
+import (
+	"errors"
+	"io"
+)
+
+// The *Fixed functions always use the int or uint format of the width in
+// their name, whatever the value, so the encoding keeps its size. A value
+// written this way can be updated in place later with PatchInt64 or
+// PatchUInt64.
+
+func AppendUInt16Fixed(dst []byte, value uint16) []byte {
+	return append(dst, MP_UINT16, uint8(value>>8), uint8(value))
+}
+
+func AppendUInt32Fixed(dst []byte, value uint32) []byte {
+	return append(dst, MP_UINT32,
+		uint8(value>>24), uint8(value>>16), uint8(value>>8), uint8(value))
+}
+
+func AppendUInt64Fixed(dst []byte, value uint64) []byte {
+	return append(dst, MP_UINT64,
+		uint8(value>>56), uint8(value>>48), uint8(value>>40), uint8(value>>32),
+		uint8(value>>24), uint8(value>>16), uint8(value>>8), uint8(value))
+}
+
+func AppendInt16Fixed(dst []byte, value int16) []byte {
+	return append(dst, MP_INT16, uint8(value>>8), uint8(value))
+}
+
+func AppendInt32Fixed(dst []byte, value int32) []byte {
+	return append(dst, MP_INT32,
+		uint8(value>>24), uint8(value>>16), uint8(value>>8), uint8(value))
+}
+
+func AppendInt64Fixed(dst []byte, value int64) []byte {
+	return append(dst, MP_INT64,
+		uint8(value>>56), uint8(value>>48), uint8(value>>40), uint8(value>>32),
+		uint8(value>>24), uint8(value>>16), uint8(value>>8), uint8(value))
+}
+
+func PackUInt16Fixed(writer io.Writer, value uint16) (count int, err error) {
+	var b [3]byte
+	return writer.Write(AppendUInt16Fixed(b[:0], value))
+}
+
+func PackUInt32Fixed(writer io.Writer, value uint32) (count int, err error) {
+	var b [5]byte
+	return writer.Write(AppendUInt32Fixed(b[:0], value))
+}
+
+func PackUInt64Fixed(writer io.Writer, value uint64) (count int, err error) {
+	var b [9]byte
+	return writer.Write(AppendUInt64Fixed(b[:0], value))
+}
+
+func PackInt16Fixed(writer io.Writer, value int16) (count int, err error) {
+	var b [3]byte
+	return writer.Write(AppendInt16Fixed(b[:0], value))
+}
+
+func PackInt32Fixed(writer io.Writer, value int32) (count int, err error) {
+	var b [5]byte
+	return writer.Write(AppendInt32Fixed(b[:0], value))
+}
+
+func PackInt64Fixed(writer io.Writer, value int64) (count int, err error) {
+	var b [9]byte
+	return writer.Write(AppendInt64Fixed(b[:0], value))
+}
+
+// ErrNegativeOffset is returned by PatchInt64 and PatchUInt64 for an
+// offset below zero.
+var ErrNegativeOffset = errors.New("negative offset")
+
+// patchFormat returns the payload size of the int or uint at offset, and
+// whether it is signed.
+func patchFormat(buf []byte, offset int) (size int, signed bool, target string, err error) {
+	if offset < 0 {
+		return 0, false, "", ErrNegativeOffset
+	}
+	if offset >= len(buf) {
+		return 0, false, "", truncated(buf, offset, 1)
+	}
+
+	switch buf[offset] {
+	case MP_UINT8:
+		size, target = 1, "uint8"
+	case MP_UINT16:
+		size, target = 2, "uint16"
+	case MP_UINT32:
+		size, target = 4, "uint32"
+	case MP_UINT64:
+		size, target = 8, "uint64"
+	case MP_INT8:
+		size, signed, target = 1, true, "int8"
+	case MP_INT16:
+		size, signed, target = 2, true, "int16"
+	case MP_INT32:
+		size, signed, target = 4, true, "int32"
+	case MP_INT64:
+		size, signed, target = 8, true, "int64"
+	default:
+		return 0, false, "", mismatch(buf, offset, "sized int")
+	}
+
+	if size >= len(buf)-offset {
+		return 0, false, "", truncated(buf, offset, uint64(1+size))
+	}
+	return size, signed, target, nil
+}
+
+func putUint(b []byte, value uint64) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = uint8(value)
+		value >>= 8
+	}
+}
+
+// PatchInt64 overwrites the int or uint encoded at offset in buf with
+// value, keeping its format and size. It returns an OverflowError if value
+// does not fit the format, and leaves buf unchanged on error.
+func PatchInt64(buf []byte, offset int, value int64) error {
+	size, signed, target, err := patchFormat(buf, offset)
+	if err != nil {
+		return err
+	}
+
+	bits := uint(8 * size)
+	if signed && (value < -1<<(bits-1) || value > 1<<(bits-1)-1) ||
+		!signed && (value < 0 || bits < 64 && value > 1<<bits-1) {
+		return &OverflowError{Offset: offset, Value: value, Target: target}
+	}
+
+	putUint(buf[offset+1:offset+1+size], uint64(value))
+	return nil
+}
+
+// PatchUInt64 is PatchInt64 for unsigned values.
+func PatchUInt64(buf []byte, offset int, value uint64) error {
+	size, signed, target, err := patchFormat(buf, offset)
+	if err != nil {
+		return err
+	}
+
+	bits := uint(8 * size)
+	if signed && value > 1<<(bits-1)-1 || !signed && bits < 64 && value > 1<<bits-1 {
+		return &OverflowError{Offset: offset, Value: value, Target: target}
+	}
+
+	putUint(buf[offset+1:offset+1+size], value)
+	return nil
+}
@@ -0,0 +1,83 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPackFixed(t *testing.T) {
+	b := &bytes.Buffer{}
+	PackUInt16Fixed(b, 1)
+	PackUInt32Fixed(b, 1)
+	PackUInt64Fixed(b, 1)
+	PackInt16Fixed(b, -1)
+	PackInt32Fixed(b, -1)
+	PackInt64Fixed(b, 1)
+
+	if bytes.Compare(b.Bytes(), []byte{0xcd, 0x0, 0x1, 0xce, 0x0, 0x0, 0x0, 0x1,
+		0xcf, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0xd1, 0xff, 0xff,
+		0xd2, 0xff, 0xff, 0xff, 0xff, 0xd3, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1}) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+
+	offset := uint32(0)
+	for _, expected := range []int64{1, 1, 1, -1, -1, 1} {
+		if v, err := UnpackInt64(b.Bytes(), &offset); err != nil || v != expected {
+			t.Error("wrong output", v, err)
+		}
+	}
+}
+
+func TestPatch(t *testing.T) {
+	b := AppendArrayHeader(nil, 2)
+	first := len(b)
+	b = AppendUInt32Fixed(b, 0)
+	second := len(b)
+	b = AppendInt16Fixed(b, 0)
+
+	if err := PatchUInt64(b, first, 70000); err != nil {
+		t.Error("err != nil", err)
+	}
+	if err := PatchInt64(b, second, -300); err != nil {
+		t.Error("err != nil", err)
+	}
+
+	var out []int
+	if err := Unmarshal(b, &out); err != nil || len(out) != 2 || out[0] != 70000 || out[1] != -300 {
+		t.Error("wrong output", out, err)
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	b := AppendInt16Fixed([]byte{0x1}, 5)
+
+	var e *OverflowError
+	if err := PatchInt64(b, 1, 40000); !errors.As(err, &e) || e.Target != "int16" {
+		t.Error("expected overflow", err)
+	}
+	if err := PatchUInt64(b, 1, 1<<15); !errors.As(err, &e) {
+		t.Error("expected overflow", err)
+	}
+	if err := PatchInt64(b, 0, 1); !isMismatch(err, 0x1) {
+		t.Error("expected mismatch", err)
+	}
+	if err := PatchInt64(b[:2], 1, 1); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("expected truncation", err)
+	}
+	if err := PatchInt64(b, -1, 1); err != ErrNegativeOffset {
+		t.Error("expected ErrNegativeOffset", err)
+	}
+	if err := PatchUInt64(b, -3, 1); err != ErrNegativeOffset {
+		t.Error("expected ErrNegativeOffset", err)
+	}
+
+	u := AppendUInt16Fixed(nil, 5)
+	if err := PatchInt64(u, 0, -1); !errors.As(err, &e) {
+		t.Error("expected overflow", err)
+	}
+
+	if bytes.Compare(b, []byte{0x1, 0xd1, 0x0, 0x5}) != 0 || bytes.Compare(u, []byte{0xcd, 0x0, 0x5}) != 0 {
+		t.Error("buffer changed on error", b, u)
+	}
+}
@@ -0,0 +1,514 @@
+package msgpack
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONOptions choose how AppendJSON writes the values JSON has no direct
+// way to represent. The zero value writes bin data as base64 strings, other
+// map keys as strings, ext values as objects and fails on NaN and infinite
+// floats like encoding/json does.
+type JSONOptions struct {
+	Binary JSONBinaryMode
+	Keys   JSONKeyMode
+	Floats JSONFloatMode
+	Ext    JSONExtMode
+}
+
+type JSONBinaryMode int
+
+const (
+	// JSONBinaryBase64 writes bin data as a string in standard base64.
+	JSONBinaryBase64 JSONBinaryMode = iota
+	// JSONBinaryArray writes bin data as an array of byte values.
+	JSONBinaryArray
+	// JSONBinaryError fails on bin data.
+	JSONBinaryError
+)
+
+type JSONKeyMode int
+
+const (
+	// JSONKeysString writes nil, boolean and number keys as their JSON
+	// text in a string, such as "1" or "true".
+	JSONKeysString JSONKeyMode = iota
+	// JSONKeysError fails on keys that are not str or raw data.
+	JSONKeysError
+)
+
+type JSONFloatMode int
+
+const (
+	// JSONFloatsError fails on NaN and infinite floats.
+	JSONFloatsError JSONFloatMode = iota
+	// JSONFloatsNull writes them as null.
+	JSONFloatsNull
+	// JSONFloatsString writes them as the strings "NaN", "Infinity" and
+	// "-Infinity".
+	JSONFloatsString
+)
+
+type JSONExtMode int
+
+const (
+	// JSONExtObject writes ext values as {"type":code,"data":"base64"}.
+	JSONExtObject JSONExtMode = iota
+	// JSONExtError fails on ext values.
+	JSONExtError
+)
+
+// UnsupportedValueError reports a value that JSONOptions do not let
+// AppendJSON write.
+type UnsupportedValueError struct {
+	Offset int
+	Value  string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "cannot write " + e.Value + " at offset " + strconv.Itoa(e.Offset) + " as JSON"
+}
+
+type jsonFrame struct {
+	n, i  uint64
+	isMap bool
+}
+
+// AppendJSON appends the next value as JSON text to dst. It works from the
+// headers without building the value, so integers keep their precision.
+// Timestamps are written as RFC 3339 strings and str data that is not
+// valid UTF-8 gets U+FFFD in place of the bad bytes.
+func (c *Cursor) AppendJSON(dst []byte, opts JSONOptions) (out []byte, err error) {
+	defer c.rollback(c.off, &err)
+
+	out = dst
+	var stack []jsonFrame
+	for {
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.i == top.n {
+				if top.isMap {
+					out = append(out, '}')
+				} else {
+					out = append(out, ']')
+				}
+				if stack = stack[:len(stack)-1]; len(stack) == 0 {
+					return out, nil
+				}
+				continue
+			}
+
+			if top.i > 0 {
+				if top.isMap && top.i%2 == 1 {
+					out = append(out, ':')
+				} else {
+					out = append(out, ',')
+				}
+			}
+			isKey = top.isMap && top.i%2 == 0
+			top.i++
+		}
+
+		if c.off >= len(c.buf) {
+			return dst, truncated(c.buf, c.off, 1)
+		}
+
+		switch t := typeOfHeader(c.buf[c.off]); {
+		case isKey:
+			out, err = c.appendJSONKey(out, &opts)
+		case t == ArrayType:
+			var n uint32
+			if n, err = c.UnpackArrayHeader(); err == nil {
+				out = append(out, '[')
+				stack = append(stack, jsonFrame{n: uint64(n)})
+			}
+		case t == MapType:
+			var n uint32
+			if n, err = c.UnpackMapHeader(); err == nil {
+				out = append(out, '{')
+				stack = append(stack, jsonFrame{n: 2 * uint64(n), isMap: true})
+			}
+		default:
+			out, err = c.appendJSONScalar(out, &opts)
+		}
+		if err != nil {
+			return dst, err
+		}
+		if len(stack) == 0 {
+			return out, nil
+		}
+	}
+}
+
+func (c *Cursor) appendJSONScalar(dst []byte, opts *JSONOptions) ([]byte, error) {
+	off := c.off
+	switch typeOfHeader(c.buf[off]) {
+	case NilType:
+		c.off++
+		return append(dst, "null"...), nil
+	case BoolType:
+		v, err := c.UnpackBool()
+		return strconv.AppendBool(dst, v), err
+	case IntType:
+		v, err := c.UnpackInt64()
+		return strconv.AppendInt(dst, v, 10), err
+	case UintType:
+		v, err := c.UnpackUInt64()
+		return strconv.AppendUint(dst, v, 10), err
+	case Float32Type:
+		v, err := c.UnpackFloat()
+		if err != nil {
+			return dst, err
+		}
+		return appendJSONFloat(dst, float64(v), 32, off, opts)
+	case Float64Type:
+		v, err := c.UnpackDouble()
+		if err != nil {
+			return dst, err
+		}
+		return appendJSONFloat(dst, v, 64, off, opts)
+	case RawType:
+		v, err := c.UnpackRawBuffer()
+		return appendJSONString(dst, v), err
+	case BinType:
+		v, err := c.UnpackRawBuffer()
+		if err != nil {
+			return dst, err
+		}
+		switch opts.Binary {
+		case JSONBinaryArray:
+			dst = append(dst, '[')
+			for i, b := range v {
+				if i > 0 {
+					dst = append(dst, ',')
+				}
+				dst = strconv.AppendUint(dst, uint64(b), 10)
+			}
+			return append(dst, ']'), nil
+		case JSONBinaryError:
+			return dst, &UnsupportedValueError{off, "bin data"}
+		}
+		return appendJSONBase64(dst, v), nil
+	case ExtType:
+		code, payload, err := c.UnpackExt()
+		if err != nil {
+			return dst, err
+		}
+		if code == MP_EXT_TIMESTAMP {
+			t, err := decodeTimestamp(c.buf, off, payload)
+			if err != nil {
+				c.off = off
+				return dst, err
+			}
+			dst = append(dst, '"')
+			dst = t.UTC().AppendFormat(dst, time.RFC3339Nano)
+			return append(dst, '"'), nil
+		}
+		if opts.Ext == JSONExtError {
+			return dst, &UnsupportedValueError{off, "ext " + strconv.Itoa(int(code))}
+		}
+		dst = append(dst, `{"type":`...)
+		dst = strconv.AppendInt(dst, int64(code), 10)
+		dst = append(dst, `,"data":`...)
+		dst = appendJSONBase64(dst, payload)
+		return append(dst, '}'), nil
+	}
+	return dst, mismatch(c.buf, off, "any type")
+}
+
+// appendJSONKey writes a map key, which JSON wants as a string.
+func (c *Cursor) appendJSONKey(dst []byte, opts *JSONOptions) ([]byte, error) {
+	off := c.off
+	switch t := typeOfHeader(c.buf[off]); t {
+	case RawType:
+		return c.appendJSONScalar(dst, opts)
+	case NilType, BoolType, IntType, UintType, Float32Type, Float64Type:
+		if opts.Keys == JSONKeysError {
+			return dst, &UnsupportedValueError{off, t.String() + " map key"}
+		}
+
+		o := *opts
+		o.Floats = JSONFloatsString
+		var scratch [32]byte
+		s, err := c.appendJSONScalar(scratch[:0], &o)
+		if err != nil {
+			return dst, err
+		}
+		if s[0] == '"' {
+			return append(dst, s...), nil
+		}
+		dst = append(dst, '"')
+		dst = append(dst, s...)
+		return append(dst, '"'), nil
+	case InvalidType:
+		return dst, mismatch(c.buf, off, "any type")
+	default:
+		return dst, &UnsupportedValueError{off, t.String() + " map key"}
+	}
+}
+
+// appendJSONFloat formats f the way encoding/json does.
+func appendJSONFloat(dst []byte, f float64, bits int, off int, opts *JSONOptions) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		s := "NaN"
+		if math.IsInf(f, 1) {
+			s = "Infinity"
+		} else if math.IsInf(f, -1) {
+			s = "-Infinity"
+		}
+
+		switch opts.Floats {
+		case JSONFloatsNull:
+			return append(dst, "null"...), nil
+		case JSONFloatsString:
+			dst = append(dst, '"')
+			dst = append(dst, s...)
+			return append(dst, '"'), nil
+		}
+		return dst, &UnsupportedValueError{off, "float " + s}
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// Turn e-09 into e-9.
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst, nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+func appendJSONString(dst []byte, s []byte) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 end lines in JavaScript.
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+func appendJSONBase64(dst []byte, b []byte) []byte {
+	dst = append(dst, '"')
+	n := len(dst)
+	size := base64.StdEncoding.EncodedLen(len(b))
+	for cap(dst)-n < size {
+		dst = append(dst[:cap(dst)], 0)
+	}
+	dst = dst[:n+size]
+	base64.StdEncoding.Encode(dst[n:], b)
+	return append(dst, '"')
+}
+
+// DecodeJSON reads the next value and appends it to dst as JSON text, as
+// Cursor.AppendJSON does.
+func (d *Decoder) DecodeJSON(dst []byte, opts JSONOptions) (out []byte, err error) {
+	err = d.unpackValue(func(c *Cursor) (err error) {
+		out, err = c.AppendJSON(dst, opts)
+		return err
+	})
+	return out, err
+}
+
+type jsonContainer struct {
+	start int
+	count uint32
+	isMap bool
+	value bool // the next token in a map is a value
+}
+
+// AppendFromJSON reads the next JSON value from dec and appends its
+// MessagePack encoding to dst. Objects become maps and arrays become
+// arrays, in their JSON order. Numbers written without a fraction or
+// exponent become ints, or uints above the int64 range, and other numbers
+// become float64 values. It makes dec use json.Number.
+func AppendFromJSON(dst []byte, dec *json.Decoder) ([]byte, error) {
+	dec.UseNumber()
+
+	out := dst
+	var stack []jsonContainer
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF && len(stack) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return dst, err
+		}
+
+		if delim, ok := tok.(json.Delim); len(stack) > 0 && (!ok || delim == '[' || delim == '{') {
+			// A value starts: an array counts it, a map counts every
+			// other one, its keys.
+			top := &stack[len(stack)-1]
+			if !top.isMap || !top.value {
+				if top.count == math.MaxUint32 {
+					return dst, &UnsupportedValueError{top.start, "container with more than 4294967295 elements"}
+				}
+				top.count++
+			}
+			if top.isMap {
+				top.value = !top.value
+			}
+		}
+
+		switch tok := tok.(type) {
+		case json.Delim:
+			switch tok {
+			case '[', '{':
+				stack = append(stack, jsonContainer{start: len(out), isMap: tok == '{'})
+				continue
+			}
+
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			var header [5]byte
+			var h []byte
+			if top.isMap {
+				h = AppendMapHeader(header[:0], top.count)
+			} else {
+				h = AppendArrayHeader(header[:0], top.count)
+			}
+			out = append(out, h...)
+			copy(out[top.start+len(h):], out[top.start:len(out)-len(h)])
+			copy(out[top.start:], h)
+		case nil:
+			out = AppendNil(out)
+		case bool:
+			out = AppendBool(out, tok)
+		case string:
+			out = AppendString(out, tok)
+		case json.Number:
+			if out, err = appendJSONNumber(out, tok); err != nil {
+				return dst, err
+			}
+		}
+
+		if len(stack) == 0 {
+			return out, nil
+		}
+	}
+}
+
+func appendJSONNumber(dst []byte, n json.Number) ([]byte, error) {
+	s := string(n)
+	integer := true
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '.' || c == 'e' || c == 'E' {
+			integer = false
+			break
+		}
+	}
+
+	if integer {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return AppendInt64(dst, v), nil
+		}
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return AppendUInt64(dst, v), nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return dst, err
+	}
+	return AppendDouble(dst, f), nil
+}
+
+// JSONToMsgpack reads a stream of JSON values from r and writes each to w
+// as MessagePack.
+func JSONToMsgpack(w io.Writer, r io.Reader) error {
+	dec := json.NewDecoder(r)
+
+	var buf []byte
+	for {
+		var err error
+		if buf, err = AppendFromJSON(buf[:0], dec); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if _, err = w.Write(buf); err != nil {
+			return err
+		}
+	}
+}
+
+// MsgpackToJSON reads a stream of MessagePack values from r and writes each
+// to w as a line of JSON.
+func MsgpackToJSON(w io.Writer, r io.Reader, opts JSONOptions) error {
+	d := NewDecoder(r)
+
+	var buf []byte
+	for {
+		var err error
+		if buf, err = d.DecodeJSON(buf[:0], opts); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if _, err = w.Write(append(buf, '\n')); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestAppendJSON(t *testing.T) {
+	for _, c := range []struct {
+		v    interface{}
+		json string
+	}{
+		{nil, `null`},
+		{true, `true`},
+		{int64(-9223372036854775808), `-9223372036854775808`},
+		{uint64(18446744073709551615), `18446744073709551615`},
+		{1.5, `1.5`},
+		{float32(0.1), `0.1`},
+		{1e21, `1e+21`},
+		{1e-7, `1e-7`},
+		{"a\"b\\c\n\x01<\u2028", `"a\"b\\c\n\u0001<\u2028"`},
+		{[]byte{1, 2, 3}, `"AQID"`},
+		{[]interface{}{}, `[]`},
+		{[]interface{}{1, []interface{}{"x", nil}, map[string]interface{}{}}, `[1,["x",null],{}]`},
+		{map[string]interface{}{"a": 1, "b": []int{2}}, `{"a":1,"b":[2]}`},
+		{map[int]string{-1: "x", 2: "y"}, `{"-1":"x","2":"y"}`},
+		{map[bool]int{true: 1}, `{"true":1}`},
+		{time.Unix(1, 5).UTC(), `"1970-01-01T00:00:01.000000005Z"`},
+		{Ext{5, []byte{0xff}}, `{"type":5,"data":"/w=="}`},
+	} {
+		b, err := Marshal(c.v)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+
+		cur := NewCursor(b)
+		out, err := cur.AppendJSON([]byte("x"), JSONOptions{})
+		if err != nil || string(out) != "x"+c.json || cur.Offset() != len(b) {
+			t.Errorf("wrong output for %v: %s %v", c.v, out, err)
+		}
+	}
+}
+
+func TestAppendJSONInvalidUTF8(t *testing.T) {
+	out, err := NewCursor([]byte{0xa3, 'a', 0xff, 'b'}).AppendJSON(nil, JSONOptions{})
+	if err != nil || string(out) != `"a\ufffdb"` {
+		t.Error("wrong output", string(out), err)
+	}
+}
+
+func TestAppendJSONOptions(t *testing.T) {
+	for _, c := range []struct {
+		v    interface{}
+		opts JSONOptions
+		json string
+	}{
+		{[]byte{1, 255}, JSONOptions{Binary: JSONBinaryArray}, `[1,255]`},
+		{[]byte{}, JSONOptions{Binary: JSONBinaryArray}, `[]`},
+		{math.NaN(), JSONOptions{Floats: JSONFloatsNull}, `null`},
+		{math.Inf(-1), JSONOptions{Floats: JSONFloatsString}, `"-Infinity"`},
+		{float32(math.Inf(1)), JSONOptions{Floats: JSONFloatsString}, `"Infinity"`},
+		{map[float64]int{math.Inf(1): 1, 1.5: 2}, JSONOptions{}, `{"1.5":2,"Infinity":1}`},
+	} {
+		b, err := Marshal(c.v)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+
+		out, err := NewCursor(b).AppendJSON(nil, c.opts)
+		if err != nil || string(out) != c.json {
+			t.Errorf("wrong output for %v: %s %v", c.v, out, err)
+		}
+	}
+
+	// Keys of other types are kept in the order they were written.
+	b := AppendMapHeader(nil, 2)
+	b = AppendNil(b)
+	b = AppendInt64(b, 1)
+	b = AppendDouble(b, 0.5)
+	b = AppendInt64(b, 2)
+	out, err := NewCursor(b).AppendJSON(nil, JSONOptions{})
+	if err != nil || string(out) != `{"null":1,"0.5":2}` {
+		t.Error("wrong output", string(out), err)
+	}
+}
+
+func TestAppendJSONErrors(t *testing.T) {
+	for _, c := range []struct {
+		v      interface{}
+		opts   JSONOptions
+		offset int
+	}{
+		{[]interface{}{1, []byte{1}}, JSONOptions{Binary: JSONBinaryError}, 2},
+		{[]float64{math.NaN()}, JSONOptions{}, 1},
+		{map[string]Ext{"a": {1, nil}}, JSONOptions{Ext: JSONExtError}, 3},
+		{map[int]int{1: 2}, JSONOptions{Keys: JSONKeysError}, 1},
+		{map[interface{}]int{}, JSONOptions{}, -1},
+	} {
+		b, err := Marshal(c.v)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+		if c.offset < 0 {
+			// A bin key cannot be written whatever the options.
+			b = append(AppendMapHeader(nil, 1), 0xc4, 0x1, 0x0, 0x1)
+			c.offset = 1
+		}
+
+		cur := NewCursor(b)
+		out, err := cur.AppendJSON([]byte("x"), c.opts)
+		var e *UnsupportedValueError
+		if !errors.As(err, &e) || e.Offset != c.offset || string(out) != "x" || cur.Offset() != 0 {
+			t.Errorf("wrong error for %v: %v %s", c.v, err, out)
+		}
+	}
+
+	for _, b := range [][]byte{{}, {0x92, 0x1}, {0x81, 0xa1, 'a'}, {0xd9, 0x2, 'a'}} {
+		if _, err := NewCursor(b).AppendJSON(nil, JSONOptions{}); !errors.Is(err, ErrUnpackOverflow) {
+			t.Errorf("expected overflow for % x: %v", b, err)
+		}
+	}
+
+	if _, err := NewCursor([]byte{0x91, 0xc1}).AppendJSON(nil, JSONOptions{}); err == nil {
+		t.Error("expected error for 0xc1")
+	}
+}
+
+func TestAppendJSONDeep(t *testing.T) {
+	b := bytes.Repeat([]byte{0x91}, 1000000)
+	b = append(b, 0xc0)
+
+	out, err := NewCursor(b).AppendJSON(nil, JSONOptions{})
+	if err != nil || len(out) != 2000004 || !bytes.HasPrefix(out, []byte("[[[")) {
+		t.Error("wrong output", len(out), err)
+	}
+}
+
+func TestAppendFromJSON(t *testing.T) {
+	for _, c := range []struct {
+		json string
+		v    interface{}
+	}{
+		{`null`, nil},
+		{`true`, true},
+		{`-1`, int64(-1)},
+		{`9223372036854775807`, int64(math.MaxInt64)},
+		{`18446744073709551615`, uint64(math.MaxUint64)},
+		{`18446744073709551616`, float64(18446744073709551616)},
+		{`1.5e3`, 1500.0},
+		{`"hé"`, "hé"},
+		{`[]`, []interface{}{}},
+		{`{}`, map[string]interface{}{}},
+		{`[1, [2, {"a": [3]}], {"b": {}, "c": null}]`, []interface{}{1, []interface{}{2, map[string]interface{}{"a": []int{3}}},
+			map[string]interface{}{"b": map[string]int{}, "c": nil}}},
+	} {
+		want, err := Marshal(c.v)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+
+		dec := json.NewDecoder(strings.NewReader(c.json))
+		out, err := AppendFromJSON([]byte{0xc0}, dec)
+		if err != nil || !bytes.Equal(out, append([]byte{0xc0}, want...)) {
+			t.Errorf("wrong output for %s: % x %v", c.json, out, err)
+		}
+	}
+}
+
+func TestAppendFromJSONKeyOrder(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"b": [1, 2], "a": {"x": "y"}}`))
+	out, err := AppendFromJSON(nil, dec)
+
+	want := AppendMapHeader(nil, 2)
+	want = AppendString(want, "b")
+	want = append(want, 0x92, 0x1, 0x2)
+	want = AppendString(want, "a")
+	want = append(want, 0x81, 0xa1, 'x', 0xa1, 'y')
+	if err != nil || !bytes.Equal(out, want) {
+		t.Errorf("wrong output % x %v", out, err)
+	}
+}
+
+func TestAppendFromJSONLarge(t *testing.T) {
+	n := 70000
+	s := "[" + strings.Repeat(`"abc",`, n-1) + `"abc"]`
+
+	out, err := AppendFromJSON(nil, json.NewDecoder(strings.NewReader(s)))
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	c := NewCursor(out)
+	if l, err := c.UnpackArrayHeader(); err != nil || l != uint32(n) || c.Offset() != 5 {
+		t.Error("wrong header", l, err)
+	}
+	if len(out) != 5+4*n {
+		t.Error("wrong length", len(out))
+	}
+}
+
+func TestAppendFromJSONErrors(t *testing.T) {
+	for _, s := range []string{`[1,`, `{"a" 1}`, `1e400`, ``} {
+		out, err := AppendFromJSON([]byte("x"), json.NewDecoder(strings.NewReader(s)))
+		if err == nil || string(out) != "x" {
+			t.Errorf("expected error for %q: %v", s, err)
+		}
+	}
+}
+
+func TestJSONStreams(t *testing.T) {
+	in := `{"id": 1, "tags": ["a"]} 2 "three" [18446744073709551615]`
+
+	var mp bytes.Buffer
+	if err := JSONToMsgpack(&mp, strings.NewReader(in)); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	var out bytes.Buffer
+	if err := MsgpackToJSON(&out, iotest.OneByteReader(&mp), JSONOptions{}); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	want := "{\"id\":1,\"tags\":[\"a\"]}\n2\n\"three\"\n[18446744073709551615]\n"
+	if out.String() != want {
+		t.Error("wrong output", out.String())
+	}
+
+	if err := MsgpackToJSON(&out, bytes.NewReader([]byte{0x92, 0x1}), JSONOptions{}); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+	if err := JSONToMsgpack(&out, strings.NewReader(`1 }`)); err == nil {
+		t.Error("expected error for bad JSON")
+	}
+	if err := JSONToMsgpack(&out, strings.NewReader(`1 [2`)); err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF", err)
+	}
+}
+
+func TestDecodeJSONLarge(t *testing.T) {
+	in := make([]int, 100000)
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	d := NewDecoder(&chunkReader{bytes.NewReader(b), 1400})
+	out, err := d.DecodeJSON(nil, JSONOptions{})
+	if err != nil || len(out) != 2*len(in)+1 || !bytes.HasPrefix(out, []byte("[0,0,")) {
+		t.Error("wrong output", len(out), err)
+	}
+	if _, err := d.DecodeJSON(nil, JSONOptions{}); err != io.EOF {
+		t.Error("expected io.EOF", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"math"
+	"unicode/utf8"
 )
 
 const (
@@ -38,6 +39,27 @@ const (
 	MP_RAW32  = 0xdb
 	MP_FIXRAW = 0xa0 //!< Last 5 bits is size
 
+	//! Strings, the raw types renamed by the current spec
+	MP_STR8   = 0xd9
+	MP_STR16  = MP_RAW16
+	MP_STR32  = MP_RAW32
+	MP_FIXSTR = MP_FIXRAW
+
+	//! Binary
+	MP_BIN8  = 0xc4
+	MP_BIN16 = 0xc5
+	MP_BIN32 = 0xc6
+
+	//! Extension types, followed by a signed type code
+	MP_FIXEXT1  = 0xd4
+	MP_FIXEXT2  = 0xd5
+	MP_FIXEXT4  = 0xd6
+	MP_FIXEXT8  = 0xd7
+	MP_FIXEXT16 = 0xd8
+	MP_EXT8     = 0xc7
+	MP_EXT16    = 0xc8
+	MP_EXT32    = 0xc9
+
 	/*****************************************************
 	* Container types
 	*****************************************************/
@@ -66,21 +88,8 @@ const (
 type Bytes []uint8
 
 func PackUInt64(writer io.Writer, value uint64) (count int, err error) {
-	switch {
-	case value <= MAX_7BIT:
-		return writer.Write(Bytes{MP_FIXNUM | uint8(value)})
-	case value <= MAX_8BIT:
-		return writer.Write(Bytes{MP_UINT8, uint8(value)})
-	case value <= MAX_16BIT:
-		return writer.Write(Bytes{MP_UINT16, uint8(value >> 8), uint8(value)})
-	case value <= MAX_32BIT:
-		return writer.Write(Bytes{MP_UINT32,
-			uint8(value >> 24), uint8(value >> 16), uint8(value >> 8), uint8(value)})
-	default:
-		return writer.Write(Bytes{MP_UINT64,
-			uint8(value >> 56), uint8(value >> 48), uint8(value >> 40), uint8(value >> 32),
-			uint8(value >> 24), uint8(value >> 16), uint8(value >> 8), uint8(value)})
-	}
+	var b [9]byte
+	return writer.Write(AppendUInt64(b[:0], value))
 }
 
 func PackUInt32(writer io.Writer, value uint32) (count int, err error) {
@@ -88,46 +97,42 @@ func PackUInt32(writer io.Writer, value uint32) (count int, err error) {
 }
 
 func PackInt64(writer io.Writer, value int64) (count int, err error) {
-	var n uint64
-	n = uint64(value)
-	if value >= 0 {
-		switch {
-		case value <= MAX_7BIT:
-			return writer.Write(Bytes{MP_FIXNUM | uint8(n)})
-		case value <= MAX_15BIT:
-			return writer.Write(Bytes{MP_INT16, uint8(uint64(n) >> 8), uint8(n)})
-		case value <= MAX_31BIT:
-			return writer.Write(Bytes{MP_INT32,
-				uint8(n >> 24), uint8(n >> 16),
-				uint8(n >> 8), uint8(n)})
-		default:
-			return writer.Write(Bytes{MP_INT64,
-				uint8(n >> 56), uint8(n >> 48), uint8(n >> 40), uint8(n >> 32),
-				uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)})
-		}
-	} else {
-		switch {
-		case value >= -(MAX_5BIT + 1):
-			return writer.Write(Bytes{MP_NEGATIVE_FIXNUM | uint8(value)})
-		case value >= -(int64(MAX_7BIT) + 1):
-			return writer.Write(Bytes{MP_INT8, uint8(value)})
-		case value >= -(int64(MAX_15BIT) + 1):
-			return writer.Write(Bytes{MP_INT16, uint8(n >> 8), uint8(n)})
-		case value >= -(int64(MAX_31BIT) + 1):
-			return writer.Write(Bytes{MP_INT32,
-				uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(value)})
-		default:
-			return writer.Write(Bytes{MP_INT64,
-				uint8(n >> 56), uint8(n >> 48), uint8(n >> 40), uint8(n >> 32),
-				uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)})
-		}
-	}
+	var b [9]byte
+	return writer.Write(AppendInt64(b[:0], value))
 }
 
 func PackInt32(writer io.Writer, value int32) (count int, err error) {
 	return PackInt64(writer, int64(value))
 }
 
+func PackUInt16(writer io.Writer, value uint16) (count int, err error) {
+	return PackUInt64(writer, uint64(value))
+}
+
+func PackInt16(writer io.Writer, value int16) (count int, err error) {
+	return PackInt64(writer, int64(value))
+}
+
+func PackUInt8(writer io.Writer, value uint8) (count int, err error) {
+	return PackUInt64(writer, uint64(value))
+}
+
+func PackInt8(writer io.Writer, value int8) (count int, err error) {
+	return PackInt64(writer, int64(value))
+}
+
+func PackUInt(writer io.Writer, value uint) (count int, err error) {
+	return PackUInt64(writer, uint64(value))
+}
+
+func PackInt(writer io.Writer, value int) (count int, err error) {
+	return PackInt64(writer, int64(value))
+}
+
+func PackNil(writer io.Writer) (count int, err error) {
+	return writer.Write(Bytes{MP_NULL})
+}
+
 func PackBool(writer io.Writer, value bool) (count int, err error) {
 	if value {
 		return writer.Write(Bytes{MP_TRUE})
@@ -137,230 +142,136 @@ func PackBool(writer io.Writer, value bool) (count int, err error) {
 }
 
 func PackFloat(writer io.Writer, value float32) (count int, err error) {
-	var n uint32
-	n = math.Float32bits(value)
-	return writer.Write(Bytes{MP_FLOAT,
-		uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)})
+	var b [5]byte
+	return writer.Write(AppendFloat(b[:0], value))
 }
 
 func PackDouble(writer io.Writer, value float64) (count int, err error) {
-	var n uint64
-	n = math.Float64bits(value)
-	return writer.Write(Bytes{MP_DOUBLE,
-		uint8(n >> 56), uint8(n >> 48), uint8(n >> 40), uint8(n >> 32),
-		uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)})
+	var b [9]byte
+	return writer.Write(AppendDouble(b[:0], value))
 }
 
-func PackRawBuffer(writer io.Writer, value []uint8) (count int, err error) {
-	var length uint64
-	length = uint64(len(value))
-	var n int
-	var e error
-
-	if length <= MAX_5BIT {
-		n, e = writer.Write(Bytes{MP_FIXRAW | uint8(length)})
-	} else if length <= MAX_16BIT {
-		n, e = writer.Write(Bytes{MP_RAW16, uint8(uint16(length) >> 8), uint8(length)})
-	} else {
-		n, e = writer.Write(Bytes{MP_RAW32,
-			uint8(length >> 24), uint8(length >> 16), uint8(length >> 8), uint8(length)})
+// packWithHeader writes a header followed by the payload without copying
+// the payload, and counts the bytes of both.
+func packWithHeader(writer io.Writer, header []byte, value []uint8) (count int, err error) {
+	n, e := writer.Write(header)
+	if e != nil {
+		return n, e
 	}
 
+	m, e := writer.Write(value)
+	return n + m, e
+}
+
+func PackRawBuffer(writer io.Writer, value []uint8) (count int, err error) {
+	var b [5]byte
+	return packWithHeader(writer, appendRawHeader(b[:0], uint64(len(value))), value)
+}
+
+func PackArrayHeader(writer io.Writer, length uint32) (count int, err error) {
+	var b [5]byte
+	return writer.Write(AppendArrayHeader(b[:0], length))
+}
+
+func PackMapHeader(writer io.Writer, length uint32) (count int, err error) {
+	var b [5]byte
+	return writer.Write(AppendMapHeader(b[:0], length))
+}
+
+func PackString(writer io.Writer, value string) (count int, err error) {
+	var b [5]byte
+	n, e := writer.Write(appendStringHeader(b[:0], uint64(len(value))))
 	if e != nil {
 		return n, e
 	}
 
-	return writer.Write(value)
+	m, e := io.WriteString(writer, value)
+	return n + m, e
 }
 
-var ErrUnpackOverflow = errors.New("unpack overflow")
+func PackBinary(writer io.Writer, value []uint8) (count int, err error) {
+	var b [5]byte
+	return packWithHeader(writer, appendBinaryHeader(b[:0], uint64(len(value))), value)
+}
 
-func unpackHeader(buf []byte, offset *uint32) (header uint8, err error) {
-	off := *offset
-	if int(off) >= len(buf) {
-		return 0, ErrUnpackOverflow
-	}
+// The integer and float unpackers accept every int, uint and float
+// encoding, as long as the value fits the type asked for. A whole number
+// stored as a float fits an integer type. Values that do not fit give an
+// OverflowError.
 
-	(*offset)++
-	return buf[off], nil
+func (c *Cursor) UnpackUInt64() (val uint64, err error) {
+	return c.unpackUint(math.MaxUint64, "uint64")
 }
 
-func UnpackUInt64(buf []byte, offset *uint32) (val uint64, err error) {
-	header, err := unpackHeader(buf, offset)
-	if err != nil {
-		return 0, err
-	}
+func (c *Cursor) UnpackInt64() (val int64, err error) {
+	return c.unpackInt(math.MinInt64, math.MaxInt64, "int64")
+}
 
-	if header <= MAX_7BIT {
-		return uint64(header), nil
-	}
+func (c *Cursor) UnpackUInt32() (val uint32, err error) {
+	v, err := c.unpackUint(math.MaxUint32, "uint32")
+	return uint32(v), err
+}
 
-	off := *offset
-
-	switch header {
-	case MP_UINT8:
-		(*offset)++
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return uint64(buf[off]), nil
-	case MP_UINT16:
-		(*offset) += 2
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return (uint64(buf[off]) << 8) | uint64(buf[off+1]), nil
-	case MP_UINT32:
-		(*offset) += 4
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return (uint64(buf[off]) << 24) | (uint64(buf[off+1]) << 16) |
-			(uint64(buf[off+2]) << 8) | uint64(buf[off+3]), nil
-	case MP_UINT64:
-		(*offset) += 8
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return (uint64(buf[off]) << 56) | (uint64(buf[off+1]) << 48) |
-			(uint64(buf[off+2]) << 40) | (uint64(buf[off+3]) << 32) |
-			(uint64(buf[off+4]) << 24) | (uint64(buf[off+5]) << 16) |
-			(uint64(buf[off+6]) << 8) | uint64(buf[off+7]), nil
-	default:
-		return 0, errors.New("invalid type header" + string(header))
-	}
+func (c *Cursor) UnpackInt32() (val int32, err error) {
+	v, err := c.unpackInt(math.MinInt32, math.MaxInt32, "int32")
+	return int32(v), err
 }
 
-func UnpackInt64(buf []byte, offset *uint32) (val int64, err error) {
-	header, err := unpackHeader(buf, offset)
-	if err != nil {
-		return 0, err
-	}
+func (c *Cursor) UnpackUInt16() (val uint16, err error) {
+	v, err := c.unpackUint(math.MaxUint16, "uint16")
+	return uint16(v), err
+}
 
-	if header <= MAX_7BIT {
-		return int64(header), nil
-	}
+func (c *Cursor) UnpackInt16() (val int16, err error) {
+	v, err := c.unpackInt(math.MinInt16, math.MaxInt16, "int16")
+	return int16(v), err
+}
 
-	if (header & MP_NEGATIVE_FIXNUM) == MP_NEGATIVE_FIXNUM {
-		return int64(header&0x1f) - 32, nil
-	}
+func (c *Cursor) UnpackUInt8() (val uint8, err error) {
+	v, err := c.unpackUint(math.MaxUint8, "uint8")
+	return uint8(v), err
+}
 
-	off := *offset
-
-	switch header {
-	case MP_INT8:
-		(*offset)++
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return int64(int8(buf[off])), nil
-	case MP_INT16:
-		(*offset) += 2
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return int64((int16(buf[off]) << 8) | int16(buf[off+1])), nil
-	case MP_INT32:
-		(*offset) += 4
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return int64((int32(buf[off]) << 24) | (int32(buf[off+1]) << 16) |
-			(int32(buf[off+2]) << 8) | int32(buf[off+3])), nil
-	case MP_INT64:
-		(*offset) += 8
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return (int64(buf[off]) << 56) | (int64(buf[off+1]) << 48) |
-			(int64(buf[off+2]) << 40) | (int64(buf[off+3]) << 32) |
-			(int64(buf[off+4]) << 24) | (int64(buf[off+5]) << 16) |
-			(int64(buf[off+6]) << 8) | int64(buf[off+7]), nil
-	default:
-		return 0, errors.New("invalid type header" + string(header))
-	}
+func (c *Cursor) UnpackInt8() (val int8, err error) {
+	v, err := c.unpackInt(math.MinInt8, math.MaxInt8, "int8")
+	return int8(v), err
 }
 
-func UnpackUInt32(buf []byte, offset *uint32) (val uint32, err error) {
-	header, err := unpackHeader(buf, offset)
-	if err != nil {
-		return 0, err
-	}
+func (c *Cursor) UnpackUInt() (val uint, err error) {
+	v, err := c.unpackUint(math.MaxUint, "uint")
+	return uint(v), err
+}
 
-	if header <= MAX_7BIT {
-		return uint32(header), nil
-	}
+func (c *Cursor) UnpackInt() (val int, err error) {
+	v, err := c.unpackInt(math.MinInt, math.MaxInt, "int")
+	return int(v), err
+}
 
-	off := *offset
-
-	switch header {
-	case MP_UINT8:
-		(*offset)++
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return uint32(buf[off]), nil
-	case MP_UINT16:
-		(*offset) += 2
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return (uint32(buf[off]) << 8) | uint32(buf[off+1]), nil
-	case MP_UINT32:
-		(*offset) += 4
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return (uint32(buf[off]) << 24) | (uint32(buf[off+1]) << 16) |
-			(uint32(buf[off+2]) << 8) | uint32(buf[off+3]), nil
-	default:
-		return 0, errors.New("invalid type header" + string(header))
-	}
+func (c *Cursor) IsNil() bool {
+	return c.off < len(c.buf) && c.buf[c.off] == MP_NULL
 }
 
-func UnpackInt32(buf []byte, offset *uint32) (val int32, err error) {
-	header, err := unpackHeader(buf, offset)
-	if err != nil {
-		return 0, err
-	}
+func (c *Cursor) UnpackNil() (err error) {
+	start := c.off
+	defer c.rollback(start, &err)
 
-	if header <= MAX_7BIT {
-		return int32(header), nil
+	header, err := c.header()
+	if err != nil {
+		return err
 	}
 
-	if (header & MP_NEGATIVE_FIXNUM) == MP_NEGATIVE_FIXNUM {
-		return int32(header&0x1f) - 32, nil
+	if header != MP_NULL {
+		return mismatch(c.buf, start, "nil")
 	}
 
-	off := *offset
-
-	switch header {
-	case MP_INT8:
-		(*offset)++
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return int32(int8(buf[off])), nil
-	case MP_INT16:
-		(*offset) += 2
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return int32((int16(buf[off]) << 8) | int16(buf[off+1])), nil
-	case MP_INT32:
-		(*offset) += 4
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-		return (int32(buf[off]) << 24) | (int32(buf[off+1]) << 16) |
-			(int32(buf[off+2]) << 8) | int32(buf[off+3]), nil
-	default:
-		return 0, errors.New("invalid type header" + string(header))
-	}
+	return nil
 }
 
-func UnpackBool(buf []byte, offset *uint32) (val bool, err error) {
-	header, err := unpackHeader(buf, offset)
+func (c *Cursor) UnpackBool() (val bool, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	header, err := c.header()
 	if err != nil {
 		return false, err
 	}
@@ -371,95 +282,261 @@ func UnpackBool(buf []byte, offset *uint32) (val bool, err error) {
 		return false, nil
 	}
 
-	return false, errors.New("invalid type header" + string(header))
+	return false, mismatch(c.buf, start, "bool")
 }
 
-func UnpackFloat(buf []byte, offset *uint32) (val float32, err error) {
-	header, err := unpackHeader(buf, offset)
+func (c *Cursor) UnpackFloat() (val float32, err error) {
+	v, err := c.unpackFloat(math.MaxFloat32, "float32")
+	return float32(v), err
+}
+
+func (c *Cursor) UnpackDouble() (val float64, err error) {
+	return c.unpackFloat(math.MaxFloat64, "float64")
+}
+
+func (c *Cursor) unpackBytes(str bool, bin bool, expected string) (val []byte, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	header, err := c.header()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if header == MP_FLOAT {
-		off := *offset
-		(*offset) += 4
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
+	var length uint32
+	switch {
+	case str && uint8(header&0xE0) == MP_FIXSTR:
+		length = uint32(header - MP_FIXSTR)
+	case str && header == MP_STR8, bin && header == MP_BIN8:
+		length, err = c.length(start, 1)
+	case str && header == MP_STR16, bin && header == MP_BIN16:
+		length, err = c.length(start, 2)
+	case str && header == MP_STR32, bin && header == MP_BIN32:
+		length, err = c.length(start, 4)
+	default:
+		return nil, mismatch(c.buf, start, expected)
+	}
 
-		v := (uint32(buf[off]) << 24) | (uint32(buf[off+1]) << 16) |
-			(uint32(buf[off+2]) << 8) | uint32(buf[off+3])
-		return math.Float32frombits(v), nil
+	if err != nil {
+		return nil, err
 	}
 
-	return 0, errors.New("invalid type header" + string(header))
+	off := c.off
+	if err = c.need(start, uint64(length)); err != nil {
+		return nil, err
+	}
+	return c.buf[off : off+int(length)], nil
 }
 
-func UnpackDouble(buf []byte, offset *uint32) (val float64, err error) {
-	header, err := unpackHeader(buf, offset)
+// UnpackRawBuffer accepts the old raw formats as well as every str and bin
+// format of the current spec.
+func (c *Cursor) UnpackRawBuffer() (val []byte, err error) {
+	return c.unpackBytes(true, true, "raw")
+}
+
+func (c *Cursor) UnpackString() (val string, err error) {
+	b, err := c.unpackBytes(true, false, "str")
 	if err != nil {
-		return 0, err
+		return "", err
 	}
+	return string(b), nil
+}
+
+var ErrInvalidUTF8 = errors.New("invalid utf-8 string")
 
-	if header == MP_DOUBLE {
-		off := *offset
-		(*offset) += 8
-		if int(*offset) > len(buf) {
-			return 0, ErrUnpackOverflow
-		}
-
-		v := (uint64(buf[off]) << 56) | (uint64(buf[off+1]) << 48) |
-			(uint64(buf[off+2]) << 40) | (uint64(buf[off+3]) << 32) |
-			(uint64(buf[off+4]) << 24) | (uint64(buf[off+5]) << 16) |
-			(uint64(buf[off+6]) << 8) | uint64(buf[off+7])
-		return math.Float64frombits(v), nil
+// UnpackValidString is UnpackString that also rejects strings which are not
+// valid UTF-8.
+func (c *Cursor) UnpackValidString() (val string, err error) {
+	start := c.off
+	b, err := c.unpackBytes(true, false, "str")
+	if err != nil {
+		return "", err
 	}
 
-	return 0, errors.New("invalid type header" + string(header))
+	if !utf8.Valid(b) {
+		c.off = start
+		return "", ErrInvalidUTF8
+	}
+	return string(b), nil
 }
 
-func UnpackRawBuffer(buf []byte, offset *uint32) (val []byte, err error) {
-	header, err := unpackHeader(buf, offset)
+func (c *Cursor) UnpackBinary() (val []byte, err error) {
+	return c.unpackBytes(false, true, "bin")
+}
+
+func (c *Cursor) unpackContainerHeader(fix, fixMask, h16, h32 uint8, expected string) (length uint32, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	header, err := c.header()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	if uint8(header&0xE0) == MP_FIXRAW {
-		length := uint32(header - MP_FIXRAW)
-		off := *offset
-		(*offset) += length
-		if int(*offset) > len(buf) {
-			return nil, ErrUnpackOverflow
-		}
-		return buf[off : off+length], nil
-	} else if header == MP_RAW16 {
-		off := *offset
-		(*offset) += 2
-		if int(*offset) > len(buf) {
-			return nil, ErrUnpackOverflow
-		}
-		length := (uint32(buf[off]) << 8) | uint32(buf[off+1])
-		off = *offset
-		(*offset) += length
-		if int(*offset) > len(buf) {
-			return nil, ErrUnpackOverflow
-		}
-		return buf[off : off+length], nil
-	} else if header == MP_RAW32 {
-		off := *offset
-		(*offset) += 4
-		if int(*offset) > len(buf) {
-			return nil, ErrUnpackOverflow
-		}
-		length := (uint32(buf[off]) << 24) | (uint32(buf[off+1]) << 16) |
-			(uint32(buf[off+2]) << 8) | uint32(buf[off+3])
-		off = *offset
-		(*offset) += length
-		if int(*offset) > len(buf) {
-			return nil, ErrUnpackOverflow
-		}
-		return buf[off : off+length], nil
+	switch {
+	case header&^fixMask == fix:
+		return uint32(header & fixMask), nil
+	case header == h16:
+		return c.length(start, 2)
+	case header == h32:
+		return c.length(start, 4)
+	default:
+		return 0, mismatch(c.buf, start, expected)
 	}
+}
+
+func (c *Cursor) UnpackArrayHeader() (length uint32, err error) {
+	return c.unpackContainerHeader(MP_FIXARRAY, MAX_4BIT, MP_ARRAY16, MP_ARRAY32, "array")
+}
+
+func (c *Cursor) UnpackMapHeader() (length uint32, err error) {
+	return c.unpackContainerHeader(MP_FIXMAP, MAX_4BIT, MP_MAP16, MP_MAP32, "map")
+}
+
+// The functions below unpack the value at *offset and move *offset past it.
+// Like the Cursor methods they leave *offset alone when they fail.
+
+func UnpackUInt64(buf []byte, offset *uint32) (val uint64, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackUInt64()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackInt64(buf []byte, offset *uint32) (val int64, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackInt64()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackUInt32(buf []byte, offset *uint32) (val uint32, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackUInt32()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackInt32(buf []byte, offset *uint32) (val int32, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackInt32()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackUInt16(buf []byte, offset *uint32) (val uint16, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackUInt16()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackInt16(buf []byte, offset *uint32) (val int16, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackInt16()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackUInt8(buf []byte, offset *uint32) (val uint8, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackUInt8()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackInt8(buf []byte, offset *uint32) (val int8, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackInt8()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackUInt(buf []byte, offset *uint32) (val uint, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackUInt()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackInt(buf []byte, offset *uint32) (val int, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackInt()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func IsNil(buf []byte, offset *uint32) bool {
+	c := cursorAt(buf, offset)
+	return c.IsNil()
+}
+
+func UnpackNil(buf []byte, offset *uint32) (err error) {
+	c := cursorAt(buf, offset)
+	err = c.UnpackNil()
+	*offset = uint32(c.off)
+	return err
+}
+
+func UnpackBool(buf []byte, offset *uint32) (val bool, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackBool()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackFloat(buf []byte, offset *uint32) (val float32, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackFloat()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackDouble(buf []byte, offset *uint32) (val float64, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackDouble()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackRawBuffer(buf []byte, offset *uint32) (val []byte, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackRawBuffer()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackString(buf []byte, offset *uint32) (val string, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackString()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackValidString(buf []byte, offset *uint32) (val string, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackValidString()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackBinary(buf []byte, offset *uint32) (val []byte, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackBinary()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackArrayHeader(buf []byte, offset *uint32) (length uint32, err error) {
+	c := cursorAt(buf, offset)
+	length, err = c.UnpackArrayHeader()
+	*offset = uint32(c.off)
+	return length, err
+}
 
-	return nil, errors.New("invalid type header" + string(header))
+func UnpackMapHeader(buf []byte, offset *uint32) (length uint32, err error) {
+	c := cursorAt(buf, offset)
+	length, err = c.UnpackMapHeader()
+	*offset = uint32(c.off)
+	return length, err
 }
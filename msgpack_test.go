@@ -2,6 +2,7 @@ package msgpack
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -326,7 +327,7 @@ func TestPackFloat64(t *testing.T) {
 		0xcb, 0x40, 0x74, 0x43, 0xb3, 0x45, 0xe7, 0x74, 0x7d,
 		0xcb, 0x40, 0x37, 0x2d, 0x47, 0x36, 0x6c, 0x3, 0x56,
 		0xcb, 0x40, 0x9, 0x22, 0x28, 0x6e, 0x58, 0xc4, 0x5}) != 0 {
-		t.Errorf("wrong output", b.Bytes())
+		t.Error("wrong output", b.Bytes())
 	}
 }
 
@@ -495,3 +496,344 @@ func TestUnpackRawBuffer(t *testing.T) {
 		}
 	}
 }
+
+func TestPackArrayHeader(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	for _, i := range []uint32{0, 1, 15, 16, 255, 65535, 65536, 4294967295} {
+		_, err := PackArrayHeader(b, i)
+		if err != nil {
+			t.Error("err != nil")
+		}
+	}
+
+	if bytes.Compare(b.Bytes(), []byte{
+		0x90, 0x91, 0x9f, 0xdc, 0x0, 0x10, 0xdc, 0x0, 0xff, 0xdc, 0xff, 0xff,
+		0xdd, 0x0, 0x1, 0x0, 0x0, 0xdd, 0xff, 0xff, 0xff, 0xff}) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+}
+
+func TestUnpackArrayHeader(t *testing.T) {
+	b := []byte{
+		0x90, 0x91, 0x9f, 0xdc, 0x0, 0x10, 0xdc, 0x0, 0xff, 0xdc, 0xff, 0xff,
+		0xdd, 0x0, 0x1, 0x0, 0x0, 0xdd, 0xff, 0xff, 0xff, 0xff}
+
+	v := []uint32{0, 1, 15, 16, 255, 65535, 65536, 4294967295}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		val, err := UnpackArrayHeader(b, &offset)
+		if err != nil || val != v[i] {
+			t.Error("wrong output")
+		}
+	}
+
+	offset = 0
+	if _, err := UnpackArrayHeader([]byte{0xdd, 0x0, 0x1}, &offset); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("expected overflow", err)
+	}
+
+	offset = 0
+	if _, err := UnpackArrayHeader([]byte{0x80}, &offset); err == nil {
+		t.Error("expected error for map header")
+	}
+}
+
+func TestPackMapHeader(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	for _, i := range []uint32{0, 1, 15, 16, 255, 65535, 65536, 4294967295} {
+		_, err := PackMapHeader(b, i)
+		if err != nil {
+			t.Error("err != nil")
+		}
+	}
+
+	if bytes.Compare(b.Bytes(), []byte{
+		0x80, 0x81, 0x8f, 0xde, 0x0, 0x10, 0xde, 0x0, 0xff, 0xde, 0xff, 0xff,
+		0xdf, 0x0, 0x1, 0x0, 0x0, 0xdf, 0xff, 0xff, 0xff, 0xff}) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+}
+
+func TestUnpackMapHeader(t *testing.T) {
+	b := []byte{
+		0x80, 0x81, 0x8f, 0xde, 0x0, 0x10, 0xde, 0x0, 0xff, 0xde, 0xff, 0xff,
+		0xdf, 0x0, 0x1, 0x0, 0x0, 0xdf, 0xff, 0xff, 0xff, 0xff}
+
+	v := []uint32{0, 1, 15, 16, 255, 65535, 65536, 4294967295}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		val, err := UnpackMapHeader(b, &offset)
+		if err != nil || val != v[i] {
+			t.Error("wrong output")
+		}
+	}
+
+	offset = 0
+	if _, err := UnpackMapHeader([]byte{0xde, 0x0}, &offset); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("expected overflow", err)
+	}
+
+	offset = 0
+	if _, err := UnpackMapHeader([]byte{0x90}, &offset); err == nil {
+		t.Error("expected error for array header")
+	}
+}
+
+func TestPackNil(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	_, err := PackNil(b)
+	if err != nil {
+		t.Error("err != nil")
+	}
+
+	if bytes.Compare(b.Bytes(), []byte{0xc0}) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+}
+
+func TestUnpackNil(t *testing.T) {
+	b := []byte{0xc0, 0xc3}
+
+	offset := uint32(0)
+
+	if !IsNil(b, &offset) || offset != 0 {
+		t.Error("IsNil failed")
+	}
+
+	if err := UnpackNil(b, &offset); err != nil || offset != 1 {
+		t.Error("wrong output")
+	}
+
+	if IsNil(b, &offset) {
+		t.Error("IsNil on bool")
+	}
+
+	if err := UnpackNil(b, &offset); err == nil {
+		t.Error("expected error for bool")
+	}
+
+	if IsNil(b, &offset) {
+		t.Error("IsNil past the end")
+	}
+}
+
+func TestPackString(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	for _, i := range []string{"", "hello world", string(bytes.Repeat([]byte{'a'}, 32)),
+		string(bytes.Repeat([]byte{'b'}, 256))} {
+		_, err := PackString(b, i)
+		if err != nil {
+			t.Error("err != nil")
+		}
+	}
+
+	expected := []byte{0xa0, 0xab, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64,
+		0xd9, 0x20}
+	expected = append(expected, bytes.Repeat([]byte{'a'}, 32)...)
+	expected = append(expected, 0xda, 0x1, 0x0)
+	expected = append(expected, bytes.Repeat([]byte{'b'}, 256)...)
+
+	if bytes.Compare(b.Bytes(), expected) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+}
+
+func TestUnpackString(t *testing.T) {
+	b := []byte{0xa0, 0xab, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64,
+		0xd9, 0x20}
+	b = append(b, bytes.Repeat([]byte{'a'}, 32)...)
+	b = append(b, 0xda, 0x1, 0x0)
+	b = append(b, bytes.Repeat([]byte{'b'}, 256)...)
+
+	v := []string{"", "hello world", string(bytes.Repeat([]byte{'a'}, 32)),
+		string(bytes.Repeat([]byte{'b'}, 256))}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		val, err := UnpackString(b, &offset)
+		if err != nil || val != v[i] {
+			t.Error("wrong output")
+		}
+	}
+
+	offset = 0
+	if _, err := UnpackString([]byte{0xc4, 0x1, 0x61}, &offset); err == nil {
+		t.Error("expected error for bin")
+	}
+
+	offset = 0
+	if _, err := UnpackString([]byte{0xd9, 0x3, 0x61}, &offset); !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("expected overflow", err)
+	}
+}
+
+func TestUnpackValidString(t *testing.T) {
+	b := []byte{0xa6, 0xe4, 0xbd, 0xa0, 0xe5, 0xa5, 0xbd, 0xa2, 0xff, 0xfe}
+
+	offset := uint32(0)
+
+	val, err := UnpackValidString(b, &offset)
+	if err != nil || val != "你好" {
+		t.Error("wrong output")
+	}
+
+	_, err = UnpackValidString(b, &offset)
+	if err != ErrInvalidUTF8 {
+		t.Error("expected invalid utf-8", err)
+	}
+
+	offset = 7
+	val, err = UnpackString(b, &offset)
+	if err != nil || val != "\xff\xfe" {
+		t.Error("UnpackString should not validate")
+	}
+}
+
+func TestPackBinary(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	for _, i := range [][]byte{{}, {0x1, 0x2, 0x3}, bytes.Repeat([]byte{0xff}, 256)} {
+		_, err := PackBinary(b, i)
+		if err != nil {
+			t.Error("err != nil")
+		}
+	}
+
+	expected := []byte{0xc4, 0x0, 0xc4, 0x3, 0x1, 0x2, 0x3, 0xc5, 0x1, 0x0}
+	expected = append(expected, bytes.Repeat([]byte{0xff}, 256)...)
+
+	if bytes.Compare(b.Bytes(), expected) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+}
+
+func TestUnpackBinary(t *testing.T) {
+	b := []byte{0xc4, 0x0, 0xc4, 0x3, 0x1, 0x2, 0x3, 0xc5, 0x1, 0x0}
+	b = append(b, bytes.Repeat([]byte{0xff}, 256)...)
+	b = append(b, 0xc6, 0x0, 0x0, 0x0, 0x2, 0x4, 0x5)
+
+	v := [][]byte{{}, {0x1, 0x2, 0x3}, bytes.Repeat([]byte{0xff}, 256), {0x4, 0x5}}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		val, err := UnpackBinary(b, &offset)
+		if err != nil || bytes.Compare(val, v[i]) != 0 {
+			t.Error("wrong output")
+		}
+	}
+
+	offset = 0
+	if _, err := UnpackBinary([]byte{0xa1, 0x61}, &offset); err == nil {
+		t.Error("expected error for str")
+	}
+}
+
+func TestUnpackRawBufferAllFormats(t *testing.T) {
+	b := []byte{0xa1, 0x61, 0xd9, 0x1, 0x62, 0xda, 0x0, 0x1, 0x63, 0xdb, 0x0, 0x0, 0x0, 0x1, 0x64,
+		0xc4, 0x1, 0x65, 0xc5, 0x0, 0x1, 0x66, 0xc6, 0x0, 0x0, 0x0, 0x1, 0x67}
+
+	v := []string{"a", "b", "c", "d", "e", "f", "g"}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		val, err := UnpackRawBuffer(b, &offset)
+		if err != nil || string(val) != v[i] {
+			t.Error("wrong output")
+		}
+	}
+}
+
+func TestPackCount(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	if n, err := PackRawBuffer(b, []byte("hello")); err != nil || n != 6 {
+		t.Error("wrong count for PackRawBuffer", n)
+	}
+
+	if n, err := PackRawBuffer(b, make([]byte, 70000)); err != nil || n != 70005 {
+		t.Error("wrong count for PackRawBuffer", n)
+	}
+
+	if n, err := PackString(b, "hello"); err != nil || n != 6 {
+		t.Error("wrong count for PackString", n)
+	}
+
+	if n, err := PackBinary(b, []byte("hello")); err != nil || n != 7 {
+		t.Error("wrong count for PackBinary", n)
+	}
+
+	if b.Len() != 6+70005+6+7 {
+		t.Error("wrong output length", b.Len())
+	}
+}
+
+func TestPackSmallInts(t *testing.T) {
+	b := &bytes.Buffer{}
+	PackInt8(b, -128)
+	PackInt16(b, 32767)
+	PackUInt8(b, 255)
+	PackUInt16(b, 256)
+	PackInt(b, -33)
+	PackUInt(b, 5)
+
+	if bytes.Compare(b.Bytes(), []byte{0xd0, 0x80, 0xd1, 0x7f, 0xff, 0xcc, 0xff,
+		0xcd, 0x1, 0x0, 0xd0, 0xdf, 0x5}) != 0 {
+		t.Error("wrong output", b.Bytes())
+	}
+
+	buf := b.Bytes()
+	offset := uint32(0)
+	if v, err := UnpackInt8(buf, &offset); err != nil || v != -128 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackInt16(buf, &offset); err != nil || v != 32767 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackUInt8(buf, &offset); err != nil || v != 255 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackUInt16(buf, &offset); err != nil || v != 256 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackInt(buf, &offset); err != nil || v != -33 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackUInt(buf, &offset); err != nil || v != 5 {
+		t.Error("wrong output", v, err)
+	}
+}
+
+func TestUnpackSmallIntsRange(t *testing.T) {
+	for _, c := range []struct {
+		b      []byte
+		unpack func(buf []byte, offset *uint32) error
+		target string
+	}{
+		{[]byte{0xcc, 0x80}, func(buf []byte, offset *uint32) error { _, err := UnpackInt8(buf, offset); return err }, "int8"},
+		{[]byte{0xd1, 0xff, 0x7f}, func(buf []byte, offset *uint32) error { _, err := UnpackInt8(buf, offset); return err }, "int8"},
+		{[]byte{0xcd, 0x80, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackInt16(buf, offset); return err }, "int16"},
+		{[]byte{0xcd, 0x1, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackUInt8(buf, offset); return err }, "uint8"},
+		{[]byte{0xff}, func(buf []byte, offset *uint32) error { _, err := UnpackUInt16(buf, offset); return err }, "uint16"},
+		{[]byte{0xce, 0x0, 0x1, 0x0, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackUInt16(buf, offset); return err }, "uint16"},
+		{[]byte{0xe0}, func(buf []byte, offset *uint32) error { _, err := UnpackUInt(buf, offset); return err }, "uint"},
+	} {
+		offset := uint32(0)
+		err := c.unpack(c.b, &offset)
+
+		var e *OverflowError
+		if !errors.As(err, &e) || e.Target != c.target || offset != 0 {
+			t.Errorf("wrong output for % x: %v", c.b, err)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package msgpack
+
+// The UnpackNullable* functions consume a nil in place of the typed value
+// and report it as a nil result.
+
+func (c *Cursor) UnpackNullableUInt64() (val *uint64, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackUInt64()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableInt64() (val *int64, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackInt64()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableUInt32() (val *uint32, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackUInt32()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableInt32() (val *int32, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackInt32()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableUInt16() (val *uint16, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackUInt16()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableInt16() (val *int16, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackInt16()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableUInt8() (val *uint8, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackUInt8()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableInt8() (val *int8, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackInt8()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableUInt() (val *uint, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackUInt()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableInt() (val *int, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackInt()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableBool() (val *bool, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackBool()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableFloat() (val *float32, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackFloat()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableDouble() (val *float64, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackDouble()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// UnpackNullableRawBuffer returns a nil slice for nil. An empty raw buffer
+// comes back as a non-nil, zero length slice.
+func (c *Cursor) UnpackNullableRawBuffer() (val []byte, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackRawBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (c *Cursor) UnpackNullableArrayHeader() (length *uint32, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableMapHeader() (length *uint32, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableString() (val *string, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackString()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableValidString() (val *string, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackValidString()
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Cursor) UnpackNullableBinary() (val []byte, err error) {
+	if c.IsNil() {
+		c.off++
+		return nil, nil
+	}
+
+	v, err := c.UnpackBinary()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func UnpackNullableUInt64(buf []byte, offset *uint32) (val *uint64, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableUInt64()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableInt64(buf []byte, offset *uint32) (val *int64, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableInt64()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableUInt32(buf []byte, offset *uint32) (val *uint32, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableUInt32()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableInt32(buf []byte, offset *uint32) (val *int32, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableInt32()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableUInt16(buf []byte, offset *uint32) (val *uint16, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableUInt16()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableInt16(buf []byte, offset *uint32) (val *int16, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableInt16()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableUInt8(buf []byte, offset *uint32) (val *uint8, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableUInt8()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableInt8(buf []byte, offset *uint32) (val *int8, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableInt8()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableUInt(buf []byte, offset *uint32) (val *uint, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableUInt()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableInt(buf []byte, offset *uint32) (val *int, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableInt()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableBool(buf []byte, offset *uint32) (val *bool, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableBool()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableFloat(buf []byte, offset *uint32) (val *float32, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableFloat()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableDouble(buf []byte, offset *uint32) (val *float64, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableDouble()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableRawBuffer(buf []byte, offset *uint32) (val []byte, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableRawBuffer()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableArrayHeader(buf []byte, offset *uint32) (length *uint32, err error) {
+	c := cursorAt(buf, offset)
+	length, err = c.UnpackNullableArrayHeader()
+	*offset = uint32(c.off)
+	return length, err
+}
+
+func UnpackNullableMapHeader(buf []byte, offset *uint32) (length *uint32, err error) {
+	c := cursorAt(buf, offset)
+	length, err = c.UnpackNullableMapHeader()
+	*offset = uint32(c.off)
+	return length, err
+}
+
+func UnpackNullableString(buf []byte, offset *uint32) (val *string, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableString()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableValidString(buf []byte, offset *uint32) (val *string, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableValidString()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+func UnpackNullableBinary(buf []byte, offset *uint32) (val []byte, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackNullableBinary()
+	*offset = uint32(c.off)
+	return val, err
+}
@@ -0,0 +1,264 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestUnpackNullableInt64(t *testing.T) {
+	b := []byte{0xc0, 0xd0, 0x80, 0x7f}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableInt64(b, &offset)
+	if err != nil || val != nil || offset != 1 {
+		t.Error("wrong output for nil")
+	}
+
+	val, err = UnpackNullableInt64(b, &offset)
+	if err != nil || val == nil || *val != -128 {
+		t.Error("wrong output")
+	}
+
+	val, err = UnpackNullableInt64(b, &offset)
+	if err != nil || val == nil || *val != 127 {
+		t.Error("wrong output")
+	}
+
+	_, err = UnpackNullableInt64(b, &offset)
+	if !errors.Is(err, ErrUnpackOverflow) {
+		t.Error("expected overflow", err)
+	}
+}
+
+func TestUnpackNullableUInt64(t *testing.T) {
+	b := []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xc0}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableUInt64(b, &offset)
+	if err != nil || val == nil || *val != 18446744073709551615 {
+		t.Error("wrong output")
+	}
+
+	val, err = UnpackNullableUInt64(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+}
+
+func TestUnpackNullableInt32(t *testing.T) {
+	b := []byte{0xc0, 0xe0}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableInt32(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+
+	val, err = UnpackNullableInt32(b, &offset)
+	if err != nil || val == nil || *val != -32 {
+		t.Error("wrong output")
+	}
+}
+
+func TestUnpackNullableUInt32(t *testing.T) {
+	b := []byte{0xce, 0xff, 0xff, 0xff, 0xff, 0xc0}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableUInt32(b, &offset)
+	if err != nil || val == nil || *val != 4294967295 {
+		t.Error("wrong output")
+	}
+
+	val, err = UnpackNullableUInt32(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+}
+
+func TestUnpackNullableBool(t *testing.T) {
+	b := []byte{0xc2, 0xc0, 0xc3}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableBool(b, &offset)
+	if err != nil || val == nil || *val != false {
+		t.Error("wrong output")
+	}
+
+	val, err = UnpackNullableBool(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+
+	val, err = UnpackNullableBool(b, &offset)
+	if err != nil || val == nil || *val != true {
+		t.Error("wrong output")
+	}
+}
+
+func TestUnpackNullableFloat(t *testing.T) {
+	b := []byte{0xc0, 0xca, 0x40, 0x49, 0xf, 0xda}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableFloat(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+
+	val, err = UnpackNullableFloat(b, &offset)
+	if err != nil || val == nil || *val != 3.1415926 {
+		t.Error("wrong output")
+	}
+}
+
+func TestUnpackNullableDouble(t *testing.T) {
+	b := []byte{0xcb, 0x40, 0x9, 0x22, 0x28, 0x6e, 0x58, 0xc4, 0x5, 0xc0}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableDouble(b, &offset)
+	if err != nil || val == nil || *val != 3.1416786785926 {
+		t.Error("wrong output")
+	}
+
+	val, err = UnpackNullableDouble(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+}
+
+func TestUnpackNullableRawBuffer(t *testing.T) {
+	b := []byte{0xc0, 0xa0, 0xa2, 0x68, 0x69}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableRawBuffer(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+
+	val, err = UnpackNullableRawBuffer(b, &offset)
+	if err != nil || val == nil || len(val) != 0 {
+		t.Error("wrong output for empty buffer")
+	}
+
+	val, err = UnpackNullableRawBuffer(b, &offset)
+	if err != nil || bytes.Compare(val, []byte("hi")) != 0 {
+		t.Error("wrong output")
+	}
+}
+
+func TestUnpackNullableHeaders(t *testing.T) {
+	b := []byte{0xc0, 0x93, 0xc0, 0x81}
+
+	offset := uint32(0)
+
+	length, err := UnpackNullableArrayHeader(b, &offset)
+	if err != nil || length != nil {
+		t.Error("wrong output for nil array")
+	}
+
+	length, err = UnpackNullableArrayHeader(b, &offset)
+	if err != nil || length == nil || *length != 3 {
+		t.Error("wrong output for array")
+	}
+
+	length, err = UnpackNullableMapHeader(b, &offset)
+	if err != nil || length != nil {
+		t.Error("wrong output for nil map")
+	}
+
+	length, err = UnpackNullableMapHeader(b, &offset)
+	if err != nil || length == nil || *length != 1 {
+		t.Error("wrong output for map")
+	}
+}
+
+func TestUnpackNullableString(t *testing.T) {
+	b := []byte{0xc0, 0xa2, 0x68, 0x69}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableString(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+
+	val, err = UnpackNullableString(b, &offset)
+	if err != nil || val == nil || *val != "hi" {
+		t.Error("wrong output")
+	}
+}
+
+func TestUnpackNullableValidString(t *testing.T) {
+	b := []byte{0xc0, 0xa2, 0x68, 0x69, 0xa1, 0xff}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableValidString(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+
+	val, err = UnpackNullableValidString(b, &offset)
+	if err != nil || val == nil || *val != "hi" {
+		t.Error("wrong output")
+	}
+
+	val, err = UnpackNullableValidString(b, &offset)
+	if err != ErrInvalidUTF8 || val != nil || offset != 4 {
+		t.Error("expected invalid utf-8", err, offset)
+	}
+}
+
+func TestUnpackNullableBinary(t *testing.T) {
+	b := []byte{0xc4, 0x0, 0xc0}
+
+	offset := uint32(0)
+
+	val, err := UnpackNullableBinary(b, &offset)
+	if err != nil || val == nil || len(val) != 0 {
+		t.Error("wrong output for empty buffer")
+	}
+
+	val, err = UnpackNullableBinary(b, &offset)
+	if err != nil || val != nil {
+		t.Error("wrong output for nil")
+	}
+}
+
+func TestUnpackNullableSmallInts(t *testing.T) {
+	b := []byte{0xc0, 0xd0, 0x80, 0xc0, 0xcc, 0xff}
+
+	offset := uint32(0)
+
+	i8, err := UnpackNullableInt8(b, &offset)
+	if err != nil || i8 != nil {
+		t.Error("wrong output for nil")
+	}
+
+	i8, err = UnpackNullableInt8(b, &offset)
+	if err != nil || i8 == nil || *i8 != -128 {
+		t.Error("wrong output")
+	}
+
+	u8, err := UnpackNullableUInt8(b, &offset)
+	if err != nil || u8 != nil {
+		t.Error("wrong output for nil")
+	}
+
+	if _, err = UnpackNullableInt8(b, &offset); err == nil {
+		t.Error("expected overflow")
+	}
+
+	u8, err = UnpackNullableUInt8(b, &offset)
+	if err != nil || u8 == nil || *u8 != 255 {
+		t.Error("wrong output")
+	}
+}
@@ -0,0 +1,164 @@
+package msgpack
+
+import "math"
+
+// number is a numeric value the way it was encoded. kind is IntType,
+// UintType, Float32Type or Float64Type and says which field holds it.
+type number struct {
+	kind Type
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func (n number) value() interface{} {
+	switch n.kind {
+	case IntType:
+		return n.i
+	case UintType:
+		return n.u
+	}
+	return n.f
+}
+
+// unpackNumber reads any int, uint or float encoding. expected names the
+// caller's target type in the error for other headers.
+func (c *Cursor) unpackNumber(expected string) (n number, err error) {
+	start := c.off
+	header, err := c.header()
+	if err != nil {
+		return n, err
+	}
+
+	if header <= MAX_7BIT {
+		return number{kind: UintType, u: uint64(header)}, nil
+	}
+
+	if (header & MP_NEGATIVE_FIXNUM) == MP_NEGATIVE_FIXNUM {
+		return number{kind: IntType, i: int64(header&0x1f) - 32}, nil
+	}
+
+	var size int
+	switch header {
+	case MP_UINT8, MP_INT8:
+		size = 1
+	case MP_UINT16, MP_INT16:
+		size = 2
+	case MP_UINT32, MP_INT32, MP_FLOAT:
+		size = 4
+	case MP_UINT64, MP_INT64, MP_DOUBLE:
+		size = 8
+	default:
+		return n, mismatch(c.buf, start, expected)
+	}
+
+	off := c.off
+	if err = c.need(start, uint64(size)); err != nil {
+		return n, err
+	}
+
+	var u uint64
+	for _, b := range c.buf[off : off+size] {
+		u = (u << 8) | uint64(b)
+	}
+
+	switch header {
+	case MP_UINT8, MP_UINT16, MP_UINT32, MP_UINT64:
+		return number{kind: UintType, u: u}, nil
+	case MP_INT8:
+		return number{kind: IntType, i: int64(int8(u))}, nil
+	case MP_INT16:
+		return number{kind: IntType, i: int64(int16(u))}, nil
+	case MP_INT32:
+		return number{kind: IntType, i: int64(int32(u))}, nil
+	case MP_INT64:
+		return number{kind: IntType, i: int64(u)}, nil
+	case MP_FLOAT:
+		return number{kind: Float32Type, f: float64(math.Float32frombits(uint32(u)))}, nil
+	default:
+		return number{kind: Float64Type, f: math.Float64frombits(u)}, nil
+	}
+}
+
+// unpackInt reads any numeric encoding whose value is a whole number
+// between min and max.
+func (c *Cursor) unpackInt(min, max int64, target string) (val int64, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	n, err := c.unpackNumber(target)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.kind {
+	case IntType:
+		if n.i >= min && n.i <= max {
+			return n.i, nil
+		}
+	case UintType:
+		if n.u <= uint64(max) {
+			return int64(n.u), nil
+		}
+	default:
+		// float64(max)+1 is a power of two, so the comparison is exact
+		// even where float64(max) is not.
+		if n.f == math.Trunc(n.f) && n.f >= float64(min) && n.f < float64(max)+1 {
+			return int64(n.f), nil
+		}
+	}
+	return 0, &OverflowError{Offset: start, Value: n.value(), Target: target}
+}
+
+// unpackUint reads any numeric encoding whose value is a whole number
+// between 0 and max.
+func (c *Cursor) unpackUint(max uint64, target string) (val uint64, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	n, err := c.unpackNumber(target)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.kind {
+	case IntType:
+		if n.i >= 0 && uint64(n.i) <= max {
+			return uint64(n.i), nil
+		}
+	case UintType:
+		if n.u <= max {
+			return n.u, nil
+		}
+	default:
+		if n.f == math.Trunc(n.f) && n.f >= 0 && n.f < float64(max)+1 {
+			return uint64(n.f), nil
+		}
+	}
+	return 0, &OverflowError{Offset: start, Value: n.value(), Target: target}
+}
+
+// unpackFloat reads any numeric encoding. Integers are rounded to the
+// nearest float, but finite values beyond max are rejected rather than
+// turned into infinities.
+func (c *Cursor) unpackFloat(max float64, target string) (val float64, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	n, err := c.unpackNumber(target)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.kind {
+	case IntType:
+		return float64(n.i), nil
+	case UintType:
+		return float64(n.u), nil
+	}
+
+	if math.Abs(n.f) > max && !math.IsInf(n.f, 0) {
+		return 0, &OverflowError{Offset: start, Value: n.f, Target: target}
+	}
+	return n.f, nil
+}
@@ -0,0 +1,91 @@
+package msgpack
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUnpackCrossFamily(t *testing.T) {
+	b := []byte{0xcc, 0xc8, 0xd1, 0x0, 0x5, 0xca, 0x40, 0x40, 0x0, 0x0, 0xcb, 0x40, 0x8, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+
+	offset := uint32(0)
+	if v, err := UnpackInt64(b, &offset); err != nil || v != 200 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackUInt32(b, &offset); err != nil || v != 5 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackInt32(b, &offset); err != nil || v != 3 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackUInt64(b, &offset); err != nil || v != 3 {
+		t.Error("wrong output", v, err)
+	}
+
+	offset = 0
+	if v, err := UnpackDouble(b, &offset); err != nil || v != 200 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackFloat(b, &offset); err != nil || v != 5 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackDouble(b, &offset); err != nil || v != 3 {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackFloat(b, &offset); err != nil || v != 3 {
+		t.Error("wrong output", v, err)
+	}
+}
+
+func TestUnpackOverflow(t *testing.T) {
+	for _, c := range []struct {
+		b      []byte
+		unpack func(buf []byte, offset *uint32) error
+		value  interface{}
+		target string
+	}{
+		{[]byte{0xff}, func(buf []byte, offset *uint32) error { _, err := UnpackUInt64(buf, offset); return err }, int64(-1), "uint64"},
+		{[]byte{0xcf, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackInt64(buf, offset); return err }, uint64(1 << 63), "int64"},
+		{[]byte{0xce, 0x80, 0x0, 0x0, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackInt32(buf, offset); return err }, uint64(1 << 31), "int32"},
+		{[]byte{0xd3, 0x0, 0x0, 0x0, 0x1, 0x0, 0x0, 0x0, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackUInt32(buf, offset); return err }, int64(1 << 32), "uint32"},
+		{[]byte{0xca, 0x3f, 0xc0, 0x0, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackInt64(buf, offset); return err }, 1.5, "int64"},
+		{[]byte{0xcb, 0x43, 0xe0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}, func(buf []byte, offset *uint32) error { _, err := UnpackInt64(buf, offset); return err }, float64(1 << 63), "int64"},
+		{[]byte{0xcb, 0x7f, 0xef, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, func(buf []byte, offset *uint32) error { _, err := UnpackFloat(buf, offset); return err }, math.MaxFloat64, "float32"},
+	} {
+		b := append([]byte{0xc0}, c.b...)
+		offset := uint32(1)
+		err := c.unpack(b, &offset)
+
+		var e *OverflowError
+		if !errors.As(err, &e) || offset != 1 {
+			t.Errorf("wrong output for % x: %v", c.b, err)
+		} else if e.Offset != 1 || e.Value != c.value || e.Target != c.target {
+			t.Errorf("wrong error for % x: %+v", c.b, e)
+		}
+	}
+
+	offset := uint32(0)
+	_, err := UnpackUInt32([]byte{0xff}, &offset)
+	if err == nil || err.Error() != "value -1 at offset 0 does not fit in uint32" {
+		t.Error("wrong message", err)
+	}
+}
+
+func TestUnpackFloatSpecial(t *testing.T) {
+	b := AppendDouble(nil, math.Inf(-1))
+	b = AppendDouble(b, math.NaN())
+
+	offset := uint32(0)
+	if v, err := UnpackFloat(b, &offset); err != nil || !math.IsInf(float64(v), -1) {
+		t.Error("wrong output", v, err)
+	}
+	if v, err := UnpackFloat(b, &offset); err != nil || v == v {
+		t.Error("wrong output", v, err)
+	}
+
+	offset = 0
+	if _, err := UnpackInt64(b, &offset); err == nil {
+		t.Error("expected overflow for infinity")
+	}
+}
@@ -0,0 +1,132 @@
+package msgpack
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// ExtEncodeFunc returns the ext payload for a value of a registered type.
+type ExtEncodeFunc func(v interface{}) ([]byte, error)
+
+// ExtDecodeFunc builds a value of a registered type from an ext payload.
+// The payload may point into the input and must be copied if it is kept.
+type ExtDecodeFunc func(data []byte) (interface{}, error)
+
+// An ExtRegistry maps Go types to ext type codes, so that Marshal writes
+// them as ext values and Unmarshal and UnpackValue turn those ext values
+// back into them. It is safe for concurrent use. The zero value is an
+// empty registry ready to use.
+type ExtRegistry struct {
+	mu     sync.RWMutex
+	byCode map[int8]*extEntry
+	byType map[reflect.Type]*extEntry
+}
+
+type extEntry struct {
+	code   int8
+	typ    reflect.Type
+	encode ExtEncodeFunc
+	decode ExtDecodeFunc
+}
+
+func NewExtRegistry() *ExtRegistry {
+	return &ExtRegistry{}
+}
+
+var defaultExtRegistry = NewExtRegistry()
+
+// ErrReservedExtCode is returned for the negative ext codes, which the
+// spec keeps for itself.
+var ErrReservedExtCode = errors.New("negative ext codes are reserved")
+
+// ExtConflictError reports a code or type that is registered already.
+type ExtConflictError struct {
+	Code     int8
+	Type     reflect.Type
+	Existing reflect.Type
+}
+
+func (e *ExtConflictError) Error() string {
+	if e.Type == e.Existing {
+		return "type " + e.Type.String() + " is already registered as ext code " + strconv.Itoa(int(e.Code))
+	}
+	return "ext code " + strconv.Itoa(int(e.Code)) + " is already registered for " + e.Existing.String()
+}
+
+// Register maps the type of value to code. Pointers to the type are
+// encoded through it as well.
+func (r *ExtRegistry) Register(code int8, value interface{}, encode ExtEncodeFunc, decode ExtDecodeFunc) error {
+	if code < 0 {
+		return ErrReservedExtCode
+	}
+
+	t := reflect.TypeOf(value)
+	if t == nil || encode == nil || decode == nil {
+		return errors.New("ext registration needs a value and both functions")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if x, ok := r.byType[t]; ok {
+		return &ExtConflictError{x.code, t, t}
+	}
+	if x, ok := r.byCode[code]; ok {
+		return &ExtConflictError{code, t, x.typ}
+	}
+
+	if r.byCode == nil {
+		r.byCode = make(map[int8]*extEntry)
+		r.byType = make(map[reflect.Type]*extEntry)
+	}
+	x := &extEntry{code, t, encode, decode}
+	r.byCode[code] = x
+	r.byType[t] = x
+	return nil
+}
+
+// RegisterExt registers a type in the registry used by Marshal, Unmarshal,
+// UnpackValue and any Encoder or Decoder without a registry of its own.
+func RegisterExt(code int8, value interface{}, encode ExtEncodeFunc, decode ExtDecodeFunc) error {
+	return defaultExtRegistry.Register(code, value, encode, decode)
+}
+
+func (r *ExtRegistry) lookupType(t reflect.Type) *extEntry {
+	if r == nil {
+		r = defaultExtRegistry
+	}
+	r.mu.RLock()
+	x := r.byType[t]
+	r.mu.RUnlock()
+	return x
+}
+
+func (r *ExtRegistry) lookupCode(code int8) *extEntry {
+	if r == nil {
+		r = defaultExtRegistry
+	}
+	r.mu.RLock()
+	x := r.byCode[code]
+	r.mu.RUnlock()
+	return x
+}
+
+// decodeExt decodes the payload of a registered ext value and checks that
+// the result has the registered type.
+func (x *extEntry) decodeExt(payload []byte) (interface{}, error) {
+	v, err := x.decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	if t := reflect.TypeOf(v); t != x.typ {
+		name := "nil"
+		if t != nil {
+			name = t.String()
+		}
+		return nil, errors.New("ext code " + strconv.Itoa(int(x.code)) + " decoded to " +
+			name + " instead of " + x.typ.String())
+	}
+	return v, nil
+}
@@ -0,0 +1,189 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type vector3 struct {
+	X, Y, Z float32
+}
+
+func encodeVector3(v interface{}) ([]byte, error) {
+	p := v.(vector3)
+	var b []byte
+	for _, f := range []float32{p.X, p.Y, p.Z} {
+		n := math.Float32bits(f)
+		b = append(b, uint8(n>>24), uint8(n>>16), uint8(n>>8), uint8(n))
+	}
+	return b, nil
+}
+
+func decodeVector3(data []byte) (interface{}, error) {
+	if len(data) != 12 {
+		return nil, errors.New("bad vector3")
+	}
+	var f [3]float32
+	for i := range f {
+		b := data[4*i:]
+		f[i] = math.Float32frombits(uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]))
+	}
+	return vector3{f[0], f[1], f[2]}, nil
+}
+
+type shape struct {
+	Origin vector3
+	Points []*vector3
+}
+
+func TestRegisterExt(t *testing.T) {
+	r := NewExtRegistry()
+	if err := r.Register(42, vector3{}, encodeVector3, decodeVector3); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	in := shape{vector3{1, 2, 3}, []*vector3{{4, 5, 6}, nil}}
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	enc.SetExtRegistry(r)
+	if err := enc.Encode(in); err != nil || enc.Flush() != nil {
+		t.Fatal("err != nil", err)
+	}
+	b := append([]byte(nil), buf.Bytes()...)
+	if !bytes.Contains(b, []byte{0xc7, 0xc, 42, 0x3f, 0x80, 0x0, 0x0}) {
+		t.Errorf("wrong output % x", b)
+	}
+
+	d := NewDecoder(buf)
+	d.SetExtRegistry(r)
+	var out shape
+	if err := d.Decode(&out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Error("wrong output", out, err)
+	}
+
+	v, err := UnpackValueWithOptions(b, new(uint32), ValueOptions{ExtRegistry: r})
+	if err != nil || v.(map[string]interface{})["Origin"] != (vector3{1, 2, 3}) {
+		t.Error("wrong output", v, err)
+	}
+
+	var conflict *ExtConflictError
+	if err := r.Register(42, 0, encodeVector3, decodeVector3); !errors.As(err, &conflict) || conflict.Existing != reflect.TypeOf(vector3{}) {
+		t.Error("expected code conflict", err)
+	}
+	if err := r.Register(43, vector3{}, encodeVector3, decodeVector3); !errors.As(err, &conflict) || conflict.Code != 42 {
+		t.Error("expected type conflict", err)
+	}
+	if err := RegisterExt(-5, 0, encodeVector3, decodeVector3); err != ErrReservedExtCode {
+		t.Error("expected ErrReservedExtCode", err)
+	}
+
+	// Without the registry the values are plain ext values.
+	if err := Unmarshal(b, &out); err == nil {
+		t.Error("expected error without the registry")
+	}
+}
+
+func TestRegisterExtDefault(t *testing.T) {
+	// Swap in an empty default registry so that other tests are not
+	// affected.
+	saved := defaultExtRegistry
+	defaultExtRegistry = NewExtRegistry()
+	defer func() { defaultExtRegistry = saved }()
+
+	if err := RegisterExt(42, vector3{}, encodeVector3, decodeVector3); err != nil {
+		t.Fatal("err != nil", err)
+	}
+	b, err := Marshal(vector3{1, 2, 3})
+	if err != nil || !bytes.HasPrefix(b, []byte{0xc7, 0xc, 42}) {
+		t.Errorf("wrong output % x %v", b, err)
+	}
+	var out vector3
+	if err := Unmarshal(b, &out); err != nil || out != (vector3{1, 2, 3}) {
+		t.Error("wrong output", out, err)
+	}
+}
+
+func TestExtRegistryZeroValue(t *testing.T) {
+	var r ExtRegistry
+	if x := r.lookupCode(42); x != nil {
+		t.Error("unexpected entry", x)
+	}
+	if err := r.Register(42, vector3{}, encodeVector3, decodeVector3); err != nil {
+		t.Fatal("err != nil", err)
+	}
+	if x := r.lookupType(reflect.TypeOf(vector3{})); x == nil || x.code != 42 {
+		t.Error("wrong entry", x)
+	}
+}
+
+type celsius float64
+type fahrenheit float64
+
+func TestEncoderDecoderExtRegistry(t *testing.T) {
+	a := NewExtRegistry()
+	a.Register(7, celsius(0), func(v interface{}) ([]byte, error) {
+		return AppendDouble(nil, float64(v.(celsius))), nil
+	}, func(data []byte) (interface{}, error) {
+		f, err := UnpackDouble(data, new(uint32))
+		return celsius(f), err
+	})
+
+	b := NewExtRegistry()
+	b.Register(7, fahrenheit(0), func(v interface{}) ([]byte, error) {
+		return AppendDouble(nil, float64(v.(fahrenheit))), nil
+	}, func(data []byte) (interface{}, error) {
+		f, err := UnpackDouble(data, new(uint32))
+		return fahrenheit(f*9/5 + 32), err
+	})
+
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	enc.SetExtRegistry(a)
+	enc.Encode(celsius(100))
+	enc.Encode(celsius(0))
+	enc.Flush()
+
+	d := NewDecoder(buf)
+	d.SetExtRegistry(b)
+	if v, err := d.DecodeValue(); err != nil || v != fahrenheit(212) {
+		t.Error("wrong output", v, err)
+	}
+	var f fahrenheit
+	if err := d.Decode(&f); err != nil || f != 32 {
+		t.Error("wrong output", f, err)
+	}
+
+	if _, err := Marshal(celsius(1)); err != nil {
+		t.Error("err != nil", err)
+	}
+	var c celsius
+	if err := Unmarshal(AppendExt(nil, 7, AppendDouble(nil, 1)), &c); err == nil {
+		t.Error("expected error without the registry")
+	}
+}
+
+func TestExtRegistryConcurrent(t *testing.T) {
+	r := NewExtRegistry()
+	types := []interface{}{int8(0), int16(0), int32(0), int64(0), uint8(0), uint16(0), uint32(0), uint64(0)}
+
+	var wg sync.WaitGroup
+	for i, v := range types {
+		wg.Add(1)
+		go func(code int8, v interface{}) {
+			defer wg.Done()
+			r.Register(code, v, encodeVector3, decodeVector3)
+			r.lookupCode(code)
+		}(int8(i), v)
+	}
+	wg.Wait()
+
+	for i, v := range types {
+		if x := r.lookupCode(int8(i)); x == nil || x.typ != reflect.TypeOf(v) {
+			t.Error("wrong entry for", i)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package msgpack
+
+// Skip moves the cursor past the next value, including everything nested
+// in it, without decoding it.
+func (c *Cursor) Skip() (err error) {
+	defer c.rollback(c.off, &err)
+
+	_, err = c.skipValues(1)
+	return err
+}
+
+// skipValues moves the cursor past count values. When the buffer runs out it
+// stops at the start of the value cut short and returns how many values,
+// that one included, are left, so that the scan can go on from there once
+// more data is buffered.
+func (c *Cursor) skipValues(count uint64) (uint64, error) {
+	for remaining := count; remaining > 0; remaining-- {
+		off := c.off
+		if off >= len(c.buf) {
+			return remaining, truncated(c.buf, off, 1)
+		}
+		header := c.buf[off]
+
+		var size uint64
+		switch typeOfHeader(header) {
+		case IntType, UintType:
+			switch header {
+			case MP_UINT8, MP_INT8:
+				size = 2
+			case MP_UINT16, MP_INT16:
+				size = 3
+			case MP_UINT32, MP_INT32:
+				size = 5
+			case MP_UINT64, MP_INT64:
+				size = 9
+			default:
+				size = 1
+			}
+		case NilType, BoolType:
+			size = 1
+		case Float32Type:
+			size = 5
+		case Float64Type:
+			size = 9
+		case RawType, BinType:
+			if _, err := c.UnpackRawBuffer(); err != nil {
+				return remaining, err
+			}
+			continue
+		case ExtType:
+			if _, _, err := c.UnpackExt(); err != nil {
+				return remaining, err
+			}
+			continue
+		case ArrayType:
+			n, err := c.UnpackArrayHeader()
+			if err != nil {
+				return remaining, err
+			}
+			remaining += uint64(n)
+			continue
+		case MapType:
+			n, err := c.UnpackMapHeader()
+			if err != nil {
+				return remaining, err
+			}
+			remaining += 2 * uint64(n)
+			continue
+		default:
+			return remaining, mismatch(c.buf, off, "any type")
+		}
+
+		if err := c.need(off, size); err != nil {
+			return remaining, err
+		}
+	}
+	return 0, nil
+}
+
+func Skip(buf []byte, offset *uint32) error {
+	c := cursorAt(buf, offset)
+	err := c.Skip()
+	*offset = uint32(c.off)
+	return err
+}
+
+// RawMessage is a raw encoded MessagePack value. Marshal writes it out
+// unchanged and Unmarshal stores a copy of the encoded value in it, so
+// decoding part of a message can be delayed or avoided.
+type RawMessage []byte
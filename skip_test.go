@@ -0,0 +1,114 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSkip(t *testing.T) {
+	values := [][]byte{
+		{0x1}, {0xe0}, {0xc0}, {0xc3},
+		{0xcc, 0x1}, {0xd0, 0x1}, {0xcd, 0x1, 0x2}, {0xd1, 0x1, 0x2},
+		{0xce, 0x1, 0x2, 0x3, 0x4}, {0xca, 0x1, 0x2, 0x3, 0x4},
+		{0xcf, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8}, {0xcb, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+		{0xa2, 0x61, 0x62}, {0xd9, 0x1, 0x61}, {0xda, 0x0, 0x1, 0x61}, {0xc4, 0x1, 0x1},
+		{0xd4, 0x1, 0x1}, {0xc7, 0x2, 0x1, 0x1, 0x2},
+		{0x90}, {0x80},
+		{0x92, 0x1, 0x91, 0x81, 0xa1, 0x6b, 0x92, 0xc0, 0xc3},
+		{0xde, 0x0, 0x2, 0xa1, 0x61, 0x81, 0x1, 0x2, 0xa1, 0x62, 0xdc, 0x0, 0x1, 0xd6, 0xff, 0x0, 0x0, 0x0, 0x0},
+	}
+
+	var b []byte
+	for _, v := range values {
+		b = append(b, v...)
+	}
+
+	offset := uint32(0)
+	for _, v := range values {
+		start := offset
+		if err := Skip(b, &offset); err != nil || offset-start != uint32(len(v)) {
+			t.Errorf("wrong output for % x: %d %v", v, offset-start, err)
+			return
+		}
+	}
+}
+
+func TestSkipErrors(t *testing.T) {
+	for _, b := range [][]byte{{}, {0xcd, 0x1}, {0xa2, 0x61}, {0x92, 0x1}, {0x81, 0x1},
+		{0xdd, 0xff, 0xff, 0xff, 0xff}, {0xd6, 0x1, 0x0}} {
+		offset := uint32(0)
+		if err := Skip(b, &offset); !errors.Is(err, ErrUnpackOverflow) {
+			t.Error("expected overflow", b, err)
+		}
+	}
+
+	offset := uint32(0)
+	if err := Skip([]byte{0x91, 0xc1}, &offset); err == nil {
+		t.Error("expected error for 0xc1")
+	}
+}
+
+type rawStruct struct {
+	Kind string
+	Body RawMessage
+}
+
+func TestRawMessage(t *testing.T) {
+	b, err := Marshal(map[string]interface{}{"Kind": "x", "Body": []interface{}{1, "a", nil}})
+	if err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	var out rawStruct
+	if err = Unmarshal(b, &out); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	if out.Kind != "x" || bytes.Compare(out.Body, []byte{0x93, 0x1, 0xa1, 0x61, 0xc0}) != 0 {
+		t.Errorf("wrong output % x", out.Body)
+	}
+
+	b, err = Marshal(out)
+	if err != nil || bytes.Compare(b, []byte{0x82, 0xa4, 0x4b, 0x69, 0x6e, 0x64, 0xa1, 0x78,
+		0xa4, 0x42, 0x6f, 0x64, 0x79, 0x93, 0x1, 0xa1, 0x61, 0xc0}) != 0 {
+		t.Errorf("wrong output % x %v", b, err)
+	}
+
+	if err = Unmarshal([]byte{0x81, 0xa4, 0x42, 0x6f, 0x64, 0x79, 0xc0}, &out); err != nil ||
+		bytes.Compare(out.Body, []byte{0xc0}) != 0 {
+		t.Errorf("wrong output for nil % x", out.Body)
+	}
+
+	b, err = Marshal(rawStruct{Kind: "y"})
+	if err != nil || bytes.Compare(b, []byte{0x82, 0xa4, 0x4b, 0x69, 0x6e, 0x64, 0xa1, 0x79,
+		0xa4, 0x42, 0x6f, 0x64, 0x79, 0xc0}) != 0 {
+		t.Errorf("wrong output for empty RawMessage % x", b)
+	}
+}
+
+func TestDecoderSkip(t *testing.T) {
+	b := &bytes.Buffer{}
+	PackArrayHeader(b, 2)
+	PackString(b, "skipped")
+	PackRawBuffer(b, make([]byte, 2000))
+	PackInt64(b, 42)
+
+	d := NewDecoder(iotest.OneByteReader(b))
+	if err := d.Skip(); err != nil {
+		t.Fatal("err != nil", err)
+	}
+
+	var v int
+	if err := d.Decode(&v); err != nil || v != 42 {
+		t.Error("wrong output", v, err)
+	}
+
+	var out []interface{}
+	d = NewDecoder(iotest.OneByteReader(bytes.NewReader([]byte{0x92, 0x1, 0xa1})))
+	if err := d.Decode(&out); err == nil || !reflect.DeepEqual(out, []interface{}(nil)) {
+		t.Error("partial value was decoded", out, err)
+	}
+}
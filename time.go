@@ -0,0 +1,81 @@
+package msgpack
+
+import (
+	"io"
+	"strconv"
+	"time"
+)
+
+// MP_EXT_TIMESTAMP is the type code of the timestamp extension.
+const MP_EXT_TIMESTAMP = -1
+
+type InvalidTimestampError struct {
+	Nanoseconds uint32
+}
+
+func (e *InvalidTimestampError) Error() string {
+	return "invalid timestamp nanoseconds " + strconv.FormatUint(uint64(e.Nanoseconds), 10)
+}
+
+func PackTime(writer io.Writer, t time.Time) (count int, err error) {
+	var b [15]byte
+	return writer.Write(AppendTime(b[:0], t))
+}
+
+// UnpackTime returns the time in UTC.
+func (c *Cursor) UnpackTime() (val time.Time, err error) {
+	start := c.off
+	defer c.rollback(start, &err)
+
+	typeCode, payload, err := c.UnpackExt()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if typeCode != MP_EXT_TIMESTAMP {
+		return time.Time{}, mismatch(c.buf, start, "timestamp")
+	}
+
+	return decodeTimestamp(c.buf, start, payload)
+}
+
+func UnpackTime(buf []byte, offset *uint32) (val time.Time, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackTime()
+	*offset = uint32(c.off)
+	return val, err
+}
+
+// decodeTimestamp decodes the payload of the timestamp ext at start.
+func decodeTimestamp(buf []byte, start int, payload []byte) (val time.Time, err error) {
+	var sec int64
+	var nsec uint32
+
+	switch len(payload) {
+	case 4:
+		sec = int64((uint32(payload[0]) << 24) | (uint32(payload[1]) << 16) |
+			(uint32(payload[2]) << 8) | uint32(payload[3]))
+	case 8:
+		n := (uint64(payload[0]) << 56) | (uint64(payload[1]) << 48) |
+			(uint64(payload[2]) << 40) | (uint64(payload[3]) << 32) |
+			(uint64(payload[4]) << 24) | (uint64(payload[5]) << 16) |
+			(uint64(payload[6]) << 8) | uint64(payload[7])
+		nsec = uint32(n >> 34)
+		sec = int64(n & 0x3ffffffff)
+	case 12:
+		nsec = (uint32(payload[0]) << 24) | (uint32(payload[1]) << 16) |
+			(uint32(payload[2]) << 8) | uint32(payload[3])
+		sec = (int64(payload[4]) << 56) | (int64(payload[5]) << 48) |
+			(int64(payload[6]) << 40) | (int64(payload[7]) << 32) |
+			(int64(payload[8]) << 24) | (int64(payload[9]) << 16) |
+			(int64(payload[10]) << 8) | int64(payload[11])
+	default:
+		return time.Time{}, mismatch(buf, start, "timestamp")
+	}
+
+	if nsec > 999999999 {
+		return time.Time{}, &InvalidTimestampError{Nanoseconds: nsec}
+	}
+
+	return time.Unix(sec, int64(nsec)).UTC(), nil
+}
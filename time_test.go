@@ -0,0 +1,89 @@
+package msgpack
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPackTime(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	for _, i := range []time.Time{
+		time.Unix(0, 0),
+		time.Unix(4294967295, 0),
+		time.Unix(4294967296, 0),
+		time.Unix(1, 999999999),
+		time.Unix(17179869183, 1),
+		time.Unix(17179869184, 0),
+		time.Unix(-1, 500000000)} {
+		_, err := PackTime(b, i)
+		if err != nil {
+			t.Error("err != nil")
+		}
+	}
+
+	if bytes.Compare(b.Bytes(), []byte{
+		0xd6, 0xff, 0x0, 0x0, 0x0, 0x0,
+		0xd6, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xd7, 0xff, 0x0, 0x0, 0x0, 0x1, 0x0, 0x0, 0x0, 0x0,
+		0xd7, 0xff, 0xee, 0x6b, 0x27, 0xfc, 0x0, 0x0, 0x0, 0x1,
+		0xd7, 0xff, 0x0, 0x0, 0x0, 0x7, 0xff, 0xff, 0xff, 0xff,
+		0xc7, 0xc, 0xff, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0, 0x0, 0x0,
+		0xc7, 0xc, 0xff, 0x1d, 0xcd, 0x65, 0x0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}) != 0 {
+		t.Errorf("wrong output % x", b.Bytes())
+	}
+}
+
+func TestUnpackTime(t *testing.T) {
+	b := []byte{
+		0xd6, 0xff, 0x0, 0x0, 0x0, 0x0,
+		0xd6, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xd7, 0xff, 0x0, 0x0, 0x0, 0x1, 0x0, 0x0, 0x0, 0x0,
+		0xd7, 0xff, 0xee, 0x6b, 0x27, 0xfc, 0x0, 0x0, 0x0, 0x1,
+		0xd7, 0xff, 0x0, 0x0, 0x0, 0x7, 0xff, 0xff, 0xff, 0xff,
+		0xc7, 0xc, 0xff, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0, 0x0, 0x0,
+		0xc7, 0xc, 0xff, 0x1d, 0xcd, 0x65, 0x0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+	v := []time.Time{
+		time.Unix(0, 0),
+		time.Unix(4294967295, 0),
+		time.Unix(4294967296, 0),
+		time.Unix(1, 999999999),
+		time.Unix(17179869183, 1),
+		time.Unix(17179869184, 0),
+		time.Unix(-1, 500000000)}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		val, err := UnpackTime(b, &offset)
+		if err != nil || !val.Equal(v[i]) || val.Location() != time.UTC {
+			t.Error("wrong output", val, v[i])
+		}
+	}
+}
+
+func TestUnpackTimeInvalid(t *testing.T) {
+	offset := uint32(0)
+	_, err := UnpackTime([]byte{0xd7, 0xff, 0xee, 0x6b, 0x28, 0x0, 0x0, 0x0, 0x0, 0x1}, &offset)
+	if e, ok := err.(*InvalidTimestampError); !ok || e.Nanoseconds != 1000000000 {
+		t.Error("expected InvalidTimestampError", err)
+	}
+
+	offset = 0
+	_, err = UnpackTime([]byte{0xc7, 0xc, 0xff, 0x3b, 0x9a, 0xca, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}, &offset)
+	if e, ok := err.(*InvalidTimestampError); !ok || e.Nanoseconds != 1000000000 {
+		t.Error("expected InvalidTimestampError", err)
+	}
+
+	offset = 0
+	if _, err = UnpackTime([]byte{0xd6, 0x1, 0x0, 0x0, 0x0, 0x0}, &offset); err == nil {
+		t.Error("expected error for ext type 1")
+	}
+
+	offset = 0
+	if _, err = UnpackTime([]byte{0xd5, 0xff, 0x0, 0x0}, &offset); err == nil {
+		t.Error("expected error for 2 byte payload")
+	}
+}
@@ -0,0 +1,140 @@
+package msgpack
+
+// Type is the kind of a MessagePack value as told by its header.
+type Type uint8
+
+const (
+	InvalidType Type = iota
+	NilType
+	BoolType
+	IntType // positive and negative fixnums and the int formats
+	UintType
+	Float32Type
+	Float64Type
+	RawType // str and the old raw formats
+	BinType
+	ArrayType
+	MapType
+	ExtType
+)
+
+var typeNames = [...]string{
+	InvalidType: "invalid",
+	NilType:     "nil",
+	BoolType:    "bool",
+	IntType:     "int",
+	UintType:    "uint",
+	Float32Type: "float32",
+	Float64Type: "float64",
+	RawType:     "raw",
+	BinType:     "bin",
+	ArrayType:   "array",
+	MapType:     "map",
+	ExtType:     "ext",
+}
+
+func (t Type) String() string {
+	if int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return typeNames[InvalidType]
+}
+
+func typeOfHeader(header uint8) Type {
+	switch {
+	case header <= MAX_7BIT, header >= MP_NEGATIVE_FIXNUM, header >= MP_INT8 && header <= MP_INT64:
+		return IntType
+	case uint8(header&0xF0) == MP_FIXMAP, header == MP_MAP16, header == MP_MAP32:
+		return MapType
+	case uint8(header&0xF0) == MP_FIXARRAY, header == MP_ARRAY16, header == MP_ARRAY32:
+		return ArrayType
+	case uint8(header&0xE0) == MP_FIXRAW, header == MP_STR8, header == MP_RAW16, header == MP_RAW32:
+		return RawType
+	case header == MP_NULL:
+		return NilType
+	case header == MP_TRUE, header == MP_FALSE:
+		return BoolType
+	case header >= MP_UINT8 && header <= MP_UINT64:
+		return UintType
+	case header == MP_FLOAT:
+		return Float32Type
+	case header == MP_DOUBLE:
+		return Float64Type
+	case header >= MP_BIN8 && header <= MP_BIN32:
+		return BinType
+	case header >= MP_FIXEXT1 && header <= MP_FIXEXT16, header >= MP_EXT8 && header <= MP_EXT32:
+		return ExtType
+	}
+	return InvalidType
+}
+
+type TypeInfo struct {
+	Type Type
+
+	// Length is the number of elements of an array, the number of pairs of
+	// a map and the byte length of raw, bin and ext data.
+	Length uint32
+
+	// ExtType is the type code of an ext value.
+	ExtType int8
+}
+
+// NextType looks at the next value without moving the cursor, so that the
+// caller can choose how to unpack it.
+func (c *Cursor) NextType() (info TypeInfo, err error) {
+	start := c.off
+	defer func() { c.off = start }()
+
+	if start >= len(c.buf) {
+		return info, truncated(c.buf, start, 1)
+	}
+	header := c.buf[start]
+
+	info.Type = typeOfHeader(header)
+	switch info.Type {
+	case InvalidType:
+		return info, mismatch(c.buf, start, "any type")
+	case ArrayType:
+		info.Length, err = c.UnpackArrayHeader()
+	case MapType:
+		info.Length, err = c.UnpackMapHeader()
+	case RawType, BinType:
+		c.off++
+		switch header {
+		case MP_STR8, MP_BIN8:
+			info.Length, err = c.length(start, 1)
+		case MP_RAW16, MP_BIN16:
+			info.Length, err = c.length(start, 2)
+		case MP_RAW32, MP_BIN32:
+			info.Length, err = c.length(start, 4)
+		default:
+			info.Length = uint32(header & MAX_5BIT)
+		}
+	case ExtType:
+		c.off++
+		switch header {
+		case MP_EXT8:
+			info.Length, err = c.length(start, 1)
+		case MP_EXT16:
+			info.Length, err = c.length(start, 2)
+		case MP_EXT32:
+			info.Length, err = c.length(start, 4)
+		default:
+			info.Length = 1 << (header - MP_FIXEXT1)
+		}
+		if err == nil {
+			if c.off >= len(c.buf) {
+				err = truncated(c.buf, start, uint64(c.off-start)+1)
+			} else {
+				info.ExtType = int8(c.buf[c.off])
+			}
+		}
+	}
+	return info, err
+}
+
+// NextType looks at the value at *offset without moving *offset.
+func NextType(buf []byte, offset *uint32) (info TypeInfo, err error) {
+	c := cursorAt(buf, offset)
+	return c.NextType()
+}
@@ -0,0 +1,100 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNextType(t *testing.T) {
+	for _, c := range []struct {
+		b    []byte
+		info TypeInfo
+	}{
+		{[]byte{0xc0}, TypeInfo{Type: NilType}},
+		{[]byte{0xc2}, TypeInfo{Type: BoolType}},
+		{[]byte{0x7f}, TypeInfo{Type: IntType}},
+		{[]byte{0xe0}, TypeInfo{Type: IntType}},
+		{[]byte{0xd3}, TypeInfo{Type: IntType}},
+		{[]byte{0xcc}, TypeInfo{Type: UintType}},
+		{[]byte{0xca}, TypeInfo{Type: Float32Type}},
+		{[]byte{0xcb}, TypeInfo{Type: Float64Type}},
+		{[]byte{0xa3}, TypeInfo{Type: RawType, Length: 3}},
+		{[]byte{0xd9, 0x20}, TypeInfo{Type: RawType, Length: 32}},
+		{[]byte{0xda, 0x1, 0x0}, TypeInfo{Type: RawType, Length: 256}},
+		{[]byte{0xc6, 0x0, 0x1, 0x0, 0x0}, TypeInfo{Type: BinType, Length: 65536}},
+		{[]byte{0x93}, TypeInfo{Type: ArrayType, Length: 3}},
+		{[]byte{0xdc, 0x0, 0x10}, TypeInfo{Type: ArrayType, Length: 16}},
+		{[]byte{0x82}, TypeInfo{Type: MapType, Length: 2}},
+		{[]byte{0xdf, 0x0, 0x1, 0x0, 0x0}, TypeInfo{Type: MapType, Length: 65536}},
+		{[]byte{0xd6, 0xff}, TypeInfo{Type: ExtType, Length: 4, ExtType: -1}},
+		{[]byte{0xd8, 0x5}, TypeInfo{Type: ExtType, Length: 16, ExtType: 5}},
+		{[]byte{0xc8, 0x1, 0x0, 0x7}, TypeInfo{Type: ExtType, Length: 256, ExtType: 7}},
+	} {
+		offset := uint32(0)
+		info, err := NextType(c.b, &offset)
+		if err != nil || info != c.info || offset != 0 {
+			t.Errorf("wrong output for % x: %+v %v", c.b, info, err)
+		}
+	}
+}
+
+func TestNextTypeErrors(t *testing.T) {
+	for _, b := range [][]byte{{}, {0xd9}, {0xdc, 0x1}, {0xd4}, {0xc7, 0x1}} {
+		offset := uint32(0)
+		if _, err := NextType(b, &offset); !errors.Is(err, ErrUnpackOverflow) {
+			t.Error("expected overflow", b, err)
+		}
+	}
+
+	offset := uint32(0)
+	info, err := NextType([]byte{0xc1}, &offset)
+	if err == nil || info.Type != InvalidType || info.Type.String() != "invalid" {
+		t.Error("expected error for 0xc1", info, err)
+	}
+}
+
+func TestNextTypeChoose(t *testing.T) {
+	b := []byte{0xcd, 0x1, 0x0, 0xd1, 0xff, 0x0}
+
+	offset := uint32(0)
+	var sum int64
+
+	for int(offset) < len(b) {
+		info, err := NextType(b, &offset)
+		if err != nil {
+			t.Fatal("err != nil", err)
+		}
+
+		if info.Type == UintType {
+			v, err := UnpackUInt64(b, &offset)
+			if err != nil {
+				t.Fatal("err != nil", err)
+			}
+			sum += int64(v)
+		} else {
+			v, err := UnpackInt64(b, &offset)
+			if err != nil {
+				t.Fatal("err != nil", err)
+			}
+			sum += v
+		}
+	}
+
+	if sum != 0 {
+		t.Error("wrong output", sum)
+	}
+}
+
+func TestDecoderPeekType(t *testing.T) {
+	d := NewDecoder(bytes.NewReader([]byte{0xdc, 0x0, 0x2, 0x1, 0x2}))
+
+	info, err := d.PeekType()
+	if err != nil || info.Type != ArrayType || info.Length != 2 {
+		t.Error("wrong output", info, err)
+	}
+
+	if n, err := d.DecodeArrayHeader(); err != nil || n != 2 {
+		t.Error("wrong output", n, err)
+	}
+}
@@ -0,0 +1,209 @@
+package msgpack
+
+import (
+	"errors"
+	"reflect"
+)
+
+type ValueOptions struct {
+	// AnyKeys makes maps come back as map[interface{}]interface{} instead
+	// of map[string]interface{}, so that keys need not be strings.
+	AnyKeys bool
+
+	// RawAsBytes returns str and raw data as []byte instead of string.
+	// Map keys are always strings.
+	RawAsBytes bool
+
+	// ExtRegistry turns ext values with a registered code into their Go
+	// type. The registry of RegisterExt is used when it is nil.
+	ExtRegistry *ExtRegistry
+}
+
+var (
+	ErrNonStringKey  = errors.New("map key is not a string")
+	ErrUnhashableKey = errors.New("map key is not hashable")
+)
+
+// UnpackValue decodes the next value whatever its type, looking at the
+// header to choose. It returns nil, bool, int64 for fixnums and the int
+// formats, uint64 for the uint formats, float32, float64, string for str
+// and raw data, []byte for bin data, time.Time for timestamps, the
+// registered Go type for ext codes in the registry, Ext for any other
+// extension, []interface{} for arrays and map[string]interface{} for
+// maps.
+func (c *Cursor) UnpackValue() (val interface{}, err error) {
+	return c.UnpackValueWithOptions(ValueOptions{})
+}
+
+// UnpackValueWithOptions is UnpackValue with the map and raw data types
+// chosen by opts.
+func (c *Cursor) UnpackValueWithOptions(opts ValueOptions) (val interface{}, err error) {
+	defer c.rollback(c.off, &err)
+	return c.unpackValue(&opts, 0)
+}
+
+func UnpackValue(buf []byte, offset *uint32) (val interface{}, err error) {
+	return UnpackValueWithOptions(buf, offset, ValueOptions{})
+}
+
+func UnpackValueWithOptions(buf []byte, offset *uint32, opts ValueOptions) (val interface{}, err error) {
+	c := cursorAt(buf, offset)
+	val, err = c.UnpackValueWithOptions(opts)
+	*offset = uint32(c.off)
+	return val, err
+}
+
+// unpackValue decodes a value found depth containers deep.
+func (c *Cursor) unpackValue(opts *ValueOptions, depth int) (val interface{}, err error) {
+	off := c.off
+	if off >= len(c.buf) {
+		return nil, truncated(c.buf, off, 1)
+	}
+	header := c.buf[off]
+
+	switch typeOfHeader(header) {
+	case NilType:
+		c.off++
+		return nil, nil
+	case BoolType:
+		return c.UnpackBool()
+	case UintType:
+		return c.UnpackUInt64()
+	case IntType:
+		return c.UnpackInt64()
+	case Float32Type:
+		return c.UnpackFloat()
+	case Float64Type:
+		return c.UnpackDouble()
+	case RawType:
+		if opts.RawAsBytes {
+			return c.unpackBytesCopy()
+		}
+		return c.UnpackString()
+	case BinType:
+		return c.unpackBytesCopy()
+	case ArrayType:
+		if depth == MaxDepth {
+			return nil, &DepthError{off}
+		}
+		return c.unpackArrayValue(opts, depth+1)
+	case MapType:
+		if depth == MaxDepth {
+			return nil, &DepthError{off}
+		}
+		if opts.AnyKeys {
+			return c.unpackAnyMapValue(opts, depth+1)
+		}
+		return c.unpackMapValue(opts, depth+1)
+	case ExtType:
+		typeCode, payload, err := c.UnpackExt()
+		if err != nil {
+			return nil, err
+		}
+		if typeCode == MP_EXT_TIMESTAMP {
+			return decodeTimestamp(c.buf, off, payload)
+		}
+		if x := opts.ExtRegistry.lookupCode(typeCode); x != nil {
+			return x.decodeExt(payload)
+		}
+		return Ext{typeCode, append(make([]byte, 0, len(payload)), payload...)}, nil
+	default:
+		return nil, mismatch(c.buf, off, "any type")
+	}
+}
+
+// isRawHeader reports whether the next value is raw, str or bin data.
+func (c *Cursor) isRawHeader() bool {
+	if c.off >= len(c.buf) {
+		return true
+	}
+	t := typeOfHeader(c.buf[c.off])
+	return t == RawType || t == BinType
+}
+
+func (c *Cursor) unpackBytesCopy() (val []byte, err error) {
+	b, err := c.UnpackRawBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return append(make([]byte, 0, len(b)), b...), nil
+}
+
+// checkContainerLength rejects container lengths that could not possibly
+// fit in the rest of the buffer, before anything is allocated for them.
+func (c *Cursor) checkContainerLength(n uint32) error {
+	if uint64(n) > uint64(c.Len()) {
+		return truncated(c.buf, c.off, uint64(n))
+	}
+	return nil
+}
+
+func (c *Cursor) unpackArrayValue(opts *ValueOptions, depth int) (val []interface{}, err error) {
+	n, err := c.UnpackArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkContainerLength(n); err != nil {
+		return nil, err
+	}
+
+	val = make([]interface{}, n)
+	for i := range val {
+		if val[i], err = c.unpackValue(opts, depth); err != nil {
+			return nil, err
+		}
+	}
+	return val, nil
+}
+
+func (c *Cursor) unpackMapValue(opts *ValueOptions, depth int) (val map[string]interface{}, err error) {
+	n, err := c.UnpackMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkContainerLength(n); err != nil {
+		return nil, err
+	}
+
+	val = make(map[string]interface{}, n)
+	for i := 0; i < int(n); i++ {
+		if !c.isRawHeader() {
+			return nil, ErrNonStringKey
+		}
+		key, err := c.UnpackRawBuffer()
+		if err != nil {
+			return nil, err
+		}
+		if val[string(key)], err = c.unpackValue(opts, depth); err != nil {
+			return nil, err
+		}
+	}
+	return val, nil
+}
+
+func (c *Cursor) unpackAnyMapValue(opts *ValueOptions, depth int) (val map[interface{}]interface{}, err error) {
+	n, err := c.UnpackMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkContainerLength(n); err != nil {
+		return nil, err
+	}
+
+	val = make(map[interface{}]interface{}, n)
+	for i := 0; i < int(n); i++ {
+		key, err := c.unpackValue(opts, depth)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		} else if key != nil && !reflect.TypeOf(key).Comparable() {
+			return nil, ErrUnhashableKey
+		}
+		if val[key], err = c.unpackValue(opts, depth); err != nil {
+			return nil, err
+		}
+	}
+	return val, nil
+}
@@ -0,0 +1,122 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnpackValue(t *testing.T) {
+	b := []byte{0xc0, 0xc2, 0x1, 0xff, 0xd1, 0x1, 0x0, 0xcc, 0xc8,
+		0xca, 0x3f, 0x80, 0x0, 0x0, 0xcb, 0x3f, 0xf8, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+		0xa2, 0x68, 0x69, 0xd9, 0x1, 0x61, 0xc4, 0x2, 0x1, 0x2,
+		0x92, 0x1, 0xa1, 0x62, 0x81, 0xa1, 0x6b, 0x90,
+		0xd6, 0xff, 0x0, 0x0, 0x0, 0x1, 0xd4, 0x5, 0x9}
+
+	v := []interface{}{nil, false, int64(1), int64(-1), int64(256), uint64(200),
+		float32(1), float64(1.5), "hi", "a", []byte{0x1, 0x2},
+		[]interface{}{int64(1), "b"}, map[string]interface{}{"k": []interface{}{}},
+		time.Unix(1, 0).UTC(), Ext{5, []byte{0x9}}}
+
+	offset := uint32(0)
+
+	for i := 0; i < len(v); i++ {
+		val, err := UnpackValue(b, &offset)
+		if err != nil || !reflect.DeepEqual(val, v[i]) {
+			t.Errorf("wrong output %#v %v", val, err)
+		}
+	}
+
+	if int(offset) != len(b) {
+		t.Error("wrong offset", offset)
+	}
+}
+
+func TestUnpackValueWithOptions(t *testing.T) {
+	// {1: "a", "b": [true]}
+	b := []byte{0x82, 0x1, 0xa1, 0x61, 0xa1, 0x62, 0x91, 0xc3}
+
+	offset := uint32(0)
+	if _, err := UnpackValue(b, &offset); err != ErrNonStringKey {
+		t.Error("expected ErrNonStringKey", err)
+	}
+
+	offset = 0
+	val, err := UnpackValueWithOptions(b, &offset, ValueOptions{AnyKeys: true})
+	expected := map[interface{}]interface{}{int64(1): "a", "b": []interface{}{true}}
+	if err != nil || !reflect.DeepEqual(val, expected) {
+		t.Errorf("wrong output %#v %v", val, err)
+	}
+
+	offset = 0
+	val, err = UnpackValueWithOptions(b, &offset, ValueOptions{AnyKeys: true, RawAsBytes: true})
+	expected = map[interface{}]interface{}{int64(1): []byte("a"), "b": []interface{}{true}}
+	if err != nil || !reflect.DeepEqual(val, expected) {
+		t.Errorf("wrong output %#v %v", val, err)
+	}
+
+	offset = 0
+	if _, err = UnpackValueWithOptions([]byte{0x81, 0x90, 0x1}, &offset, ValueOptions{AnyKeys: true}); err != ErrUnhashableKey {
+		t.Error("expected ErrUnhashableKey", err)
+	}
+}
+
+func TestUnpackValueErrors(t *testing.T) {
+	for _, b := range [][]byte{{}, {0x92, 0x1}, {0xdd, 0xff, 0xff, 0xff, 0xff}, {0x81, 0xa1}} {
+		offset := uint32(0)
+		if _, err := UnpackValue(b, &offset); !errors.Is(err, ErrUnpackOverflow) {
+			t.Error("expected overflow", b, err)
+		}
+	}
+
+	offset := uint32(0)
+	if _, err := UnpackValue([]byte{0xc1}, &offset); err == nil {
+		t.Error("expected error for 0xc1")
+	}
+}
+
+func TestUnpackValueDepth(t *testing.T) {
+	b := append(bytes.Repeat([]byte{0x91}, MaxDepth), 0xc0)
+	c := NewCursor(b)
+	if _, err := c.UnpackValue(); err != nil || c.Offset() != len(b) {
+		t.Error("err != nil", err)
+	}
+
+	for _, opts := range []ValueOptions{{}, {AnyKeys: true}} {
+		b = append(bytes.Repeat([]byte{0x81, 0xa1, 'a'}, MaxDepth/2), bytes.Repeat([]byte{0x91}, MaxDepth/2)...)
+		b = append(b, 0x91, 0xc0)
+		c = NewCursor(b)
+		var e *DepthError
+		if _, err := c.UnpackValueWithOptions(opts); !errors.As(err, &e) || e.Offset != len(b)-2 || c.Offset() != 0 {
+			t.Error("expected DepthError", opts, err)
+		}
+	}
+
+	b = append(bytes.Repeat([]byte{0x91}, 20000000), 0xc0)
+	if _, err := NewCursor(b).UnpackValue(); err == nil {
+		t.Error("expected error for deep value")
+	}
+}
+
+func TestDecoderDecodeValue(t *testing.T) {
+	d := NewDecoder(bytes.NewReader([]byte{0x92, 0xa1, 0x61, 0xc4, 0x1, 0x62}))
+
+	val, err := d.DecodeValueWithOptions(ValueOptions{RawAsBytes: true})
+	if err != nil || !reflect.DeepEqual(val, []interface{}{[]byte("a"), []byte("b")}) {
+		t.Errorf("wrong output %#v %v", val, err)
+	}
+}
+
+func TestMarshalExt(t *testing.T) {
+	b, err := Marshal(Ext{5, []byte{0x1, 0x2}})
+	if err != nil || bytes.Compare(b, []byte{0xd5, 0x5, 0x1, 0x2}) != 0 {
+		t.Error("wrong output", b, err)
+	}
+
+	var x Ext
+	if err = Unmarshal(b, &x); err != nil || x.Type != 5 || bytes.Compare(x.Data, []byte{0x1, 0x2}) != 0 {
+		t.Error("wrong output", x, err)
+	}
+}